@@ -0,0 +1,175 @@
+package pawapaygo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operation types of the active configuration, the keys of CurrencyConfig.OperationTypes
+const (
+	OPERATION_TYPE_DEPOSIT = "DEPOSIT"
+	OPERATION_TYPE_PAYOUT  = "PAYOUT"
+	OPERATION_TYPE_REFUND  = "REFUND"
+)
+
+// Values of OperationType.DecimalsInAmount
+const (
+	DECIMALS_IN_AMOUNT_NONE       = "NONE"
+	DECIMALS_IN_AMOUNT_TWO_PLACES = "TWO_PLACES"
+)
+
+// amountPattern matches the amount format pawaPay accepts: no sign or exponent, no leading
+// zeroes except a single one before the decimal point, and at most two decimal places
+var amountPattern = regexp.MustCompile(`^(0|[1-9][0-9]{0,14})(\.[0-9]{1,2})?$`)
+
+// Amount is an amount of money in the decimal format pawaPay uses, e.g. "1000" or "12.50".
+// It is stored in hundredths, so parsing, formatting and arithmetic are exact. The zero
+// value is the amount "0".
+type Amount struct {
+	hundredths int64
+	decimals   int // Decimal places the amount is formatted with, 0 to 2
+}
+
+// ParseAmount parses an amount such as "1000", "0.5" or "12.50". Forms pawaPay rejects,
+// like "5.", ".5", "00.5", "-1" or "1e3", and amounts with more than two decimal places
+// return an error.
+func ParseAmount(s string) (Amount, error) {
+	if !amountPattern.MatchString(s) {
+		return Amount{}, fmt.Errorf("%w: invalid amount %q", ErrInvalidInput, s)
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	// The pattern limits whole to 15 digits, so neither value overflows
+	units, _ := strconv.ParseInt(whole, 10, 64)
+	cents, _ := strconv.ParseInt((fraction + "00")[:2], 10, 64)
+
+	return Amount{hundredths: units*100 + cents, decimals: len(fraction)}, nil
+}
+
+// MustParseAmount is like ParseAmount but panics when s is invalid
+func MustParseAmount(s string) Amount {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// String formats the amount with the number of decimal places it was parsed with
+func (a Amount) String() string {
+	s := strconv.FormatInt(a.hundredths/100, 10)
+	switch a.decimals {
+	case 1:
+		s += fmt.Sprintf(".%d", a.hundredths%100/10)
+	case 2:
+		s += fmt.Sprintf(".%02d", a.hundredths%100)
+	}
+	return s
+}
+
+// Decimals returns the number of decimal places the amount is formatted with
+func (a Amount) Decimals() int {
+	return a.decimals
+}
+
+// IsZero reports whether the amount is zero
+func (a Amount) IsZero() bool {
+	return a.hundredths == 0
+}
+
+// Cmp compares the amounts and returns -1 if a is less than other, 0 if they are equal and
+// +1 if a is greater. "5" and "5.00" are equal.
+func (a Amount) Cmp(other Amount) int {
+	switch {
+	case a.hundredths < other.hundredths:
+		return -1
+	case a.hundredths > other.hundredths:
+		return 1
+	}
+	return 0
+}
+
+// Add returns the sum of the amounts, formatted with the larger number of decimal places of the two
+func (a Amount) Add(other Amount) Amount {
+	return Amount{
+		hundredths: a.hundredths + other.hundredths,
+		decimals:   max(a.decimals, other.decimals),
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so amounts are encoded as JSON strings
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *Amount) UnmarshalText(text []byte) error {
+	amount, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// FindOperationType returns the configuration of an operation type (OPERATION_TYPE_DEPOSIT,
+// OPERATION_TYPE_PAYOUT or OPERATION_TYPE_REFUND) for a provider and currency
+func (c *ActiveConfigurationResponse) FindOperationType(provider, currency, operation string) (*OperationType, error) {
+	for _, country := range c.Countries {
+		for _, p := range country.Providers {
+			if p.Provider != provider {
+				continue
+			}
+
+			for _, cur := range p.Currencies {
+				if cur.Currency != currency {
+					continue
+				}
+				op, ok := cur.OperationTypes[operation]
+				if !ok {
+					return nil, fmt.Errorf("%w: %s is not configured for %s in %s", ErrNotAllowed, operation, provider, currency)
+				}
+				return &op, nil
+			}
+			return nil, fmt.Errorf("%w: provider %s does not support currency %s", ErrInvalidInput, provider, currency)
+		}
+	}
+	return nil, fmt.Errorf("%w: provider %s is not configured", ErrInvalidInput, provider)
+}
+
+// ValidateAmount checks an amount against the active configuration of a provider, currency
+// and operation type before the request is sent, see OperationType.ValidateAmount
+func (c *ActiveConfigurationResponse) ValidateAmount(amount Amount, provider, currency, operation string) error {
+	op, err := c.FindOperationType(provider, currency, operation)
+	if err != nil {
+		return err
+	}
+	return op.ValidateAmount(amount)
+}
+
+// ValidateAmount checks the decimal places of an amount against DecimalsInAmount and its
+// value against MinTransactionLimit and MaxTransactionLimit. Limits that are missing or
+// can't be parsed are not checked.
+func (o *OperationType) ValidateAmount(amount Amount) error {
+	if message := o.checkAmount(amount); message != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidInput, message)
+	}
+	return nil
+}
+
+// checkAmount returns why amount is invalid for the operation type, or "" when it is valid
+func (o *OperationType) checkAmount(amount Amount) string {
+	if o.DecimalsInAmount == DECIMALS_IN_AMOUNT_NONE && amount.Decimals() > 0 {
+		return fmt.Sprintf("amount %s must not have decimal places", amount)
+	}
+
+	if limit, err := ParseAmount(o.MinTransactionLimit); err == nil && amount.Cmp(limit) < 0 {
+		return fmt.Sprintf("amount %s is below the minimum of %s", amount, limit)
+	}
+	if limit, err := ParseAmount(o.MaxTransactionLimit); err == nil && amount.Cmp(limit) > 0 {
+		return fmt.Sprintf("amount %s is above the maximum of %s", amount, limit)
+	}
+	return ""
+}
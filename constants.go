@@ -1,7 +1,14 @@
 package pawapaygo
 
+// MaxBulkPayoutSize is the maximum number of payouts accepted by a single bulk payout request
+const MaxBulkPayoutSize = 20
+
 const (
-	requestDepositRoute = "/deposits"
+	requestDepositRoute  = "/deposits"
+	requestPayoutRoute   = "/payouts"
+	requestRefundRoute   = "/refunds"
+	paymentPageRoute     = "/paymentpage"
+	predictProviderRoute = "/predict-provider"
 
 	// Countries & Currencies
 	CURRENCY_CODE_CAMEROON = "XAF"
@@ -135,3 +142,43 @@ const (
 	MTN_MOMO_ZMB    = "MTN_MOMO_ZMB"
 	ZAMTEL_ZMB      = "ZAMTEL_ZMB"
 )
+
+// Failure codes returned by pawaPay in FailureReason.FailureCode
+const (
+	// Rejections of the request itself
+	FAILURE_CODE_NO_AUTHENTICATION                = "NO_AUTHENTICATION"
+	FAILURE_CODE_AUTHENTICATION_ERROR             = "AUTHENTICATION_ERROR"
+	FAILURE_CODE_AUTHORISATION_ERROR              = "AUTHORISATION_ERROR"
+	FAILURE_CODE_HTTP_SIGNATURE_ERROR             = "HTTP_SIGNATURE_ERROR"
+	FAILURE_CODE_INVALID_INPUT                    = "INVALID_INPUT"
+	FAILURE_CODE_MISSING_PARAMETER                = "MISSING_PARAMETER"
+	FAILURE_CODE_UNSUPPORTED_PARAMETER            = "UNSUPPORTED_PARAMETER"
+	FAILURE_CODE_INVALID_PARAMETER                = "INVALID_PARAMETER"
+	FAILURE_CODE_INVALID_AMOUNT                   = "INVALID_AMOUNT"
+	FAILURE_CODE_AMOUNT_OUT_OF_BOUNDS             = "AMOUNT_OUT_OF_BOUNDS"
+	FAILURE_CODE_INVALID_PHONE_NUMBER             = "INVALID_PHONE_NUMBER"
+	FAILURE_CODE_INVALID_CURRENCY                 = "INVALID_CURRENCY"
+	FAILURE_CODE_INVALID_PROVIDER                 = "INVALID_PROVIDER"
+	FAILURE_CODE_DUPLICATE_METADATA_FIELD         = "DUPLICATE_METADATA_FIELD"
+	FAILURE_CODE_DEPOSITS_NOT_ALLOWED             = "DEPOSITS_NOT_ALLOWED"
+	FAILURE_CODE_PAYOUTS_NOT_ALLOWED              = "PAYOUTS_NOT_ALLOWED"
+	FAILURE_CODE_REFUNDS_NOT_ALLOWED              = "REFUNDS_NOT_ALLOWED"
+	FAILURE_CODE_PROVIDER_TEMPORARILY_UNAVAILABLE = "PROVIDER_TEMPORARILY_UNAVAILABLE"
+	FAILURE_CODE_DEPOSIT_NOT_FOUND                = "DEPOSIT_NOT_FOUND"
+	FAILURE_CODE_DEPOSIT_NOT_COMPLETED            = "DEPOSIT_NOT_COMPLETED"
+	FAILURE_CODE_ALREADY_REFUNDED                 = "ALREADY_REFUNDED"
+	FAILURE_CODE_AMOUNT_TOO_LARGE                 = "AMOUNT_TOO_LARGE"
+
+	// Failures of a transaction after it was accepted
+	FAILURE_CODE_PAYER_NOT_FOUND                  = "PAYER_NOT_FOUND"
+	FAILURE_CODE_PAYMENT_NOT_APPROVED             = "PAYMENT_NOT_APPROVED"
+	FAILURE_CODE_PAYER_LIMIT_REACHED              = "PAYER_LIMIT_REACHED"
+	FAILURE_CODE_INSUFFICIENT_BALANCE             = "INSUFFICIENT_BALANCE"
+	FAILURE_CODE_TRANSACTION_ALREADY_IN_PROCESS   = "TRANSACTION_ALREADY_IN_PROCESS"
+	FAILURE_CODE_RECIPIENT_NOT_FOUND              = "RECIPIENT_NOT_FOUND"
+	FAILURE_CODE_RECIPIENT_NOT_ALLOWED_TO_RECEIVE = "RECIPIENT_NOT_ALLOWED_TO_RECEIVE"
+	FAILURE_CODE_WALLET_LIMIT_REACHED             = "WALLET_LIMIT_REACHED"
+	FAILURE_CODE_MANUALLY_CANCELLED               = "MANUALLY_CANCELLED"
+	FAILURE_CODE_UNSPECIFIED_FAILURE              = "UNSPECIFIED_FAILURE"
+	FAILURE_CODE_UNKNOWN_ERROR                    = "UNKNOWN_ERROR"
+)
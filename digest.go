@@ -0,0 +1,99 @@
+package pawapaygo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/salticon/pawapay-go-sdk/sfv"
+)
+
+// MaxCallbackBodySize is the largest request body ContentDigestMiddleware accepts
+const MaxCallbackBodySize = 1 << 20
+
+// digestAlgorithms computes the digests supported in Content-Digest headers
+var digestAlgorithms = map[string]func([]byte) []byte{
+	"sha-256": func(b []byte) []byte { sum := sha256.Sum256(b); return sum[:] },
+	"sha-512": func(b []byte) []byte { sum := sha512.Sum512(b); return sum[:] },
+}
+
+// VerifyContentDigest checks a Content-Digest header (RFC 9530) against the raw body.
+// sha-256 and sha-512 digests are verified, other algorithms are ignored. An error is
+// returned when no supported digest is present or any supported digest doesn't match.
+func VerifyContentDigest(header string, body []byte) error {
+	if header == "" {
+		return fmt.Errorf("missing Content-Digest header")
+	}
+
+	digests, err := sfv.ParseDictionary(header)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Digest header: %v", err)
+	}
+
+	verified := 0
+	for _, member := range digests {
+		digest, ok := digestAlgorithms[member.Key]
+		if !ok {
+			continue
+		}
+
+		item, ok := member.Value.(sfv.Item)
+		expected, isBytes := item.Value.([]byte)
+		if !ok || !isBytes {
+			return fmt.Errorf("invalid %s content digest", member.Key)
+		}
+		if subtle.ConstantTimeCompare(expected, digest(body)) != 1 {
+			return fmt.Errorf("%s content digest does not match the body", member.Key)
+		}
+		verified++
+	}
+
+	if verified == 0 {
+		return fmt.Errorf("unsupported content digest algorithm in %q", header)
+	}
+	return nil
+}
+
+type rawBodyKey struct{}
+
+// ContentDigestMiddleware buffers the request body so digests and signatures are checked
+// against the exact bytes received. When a Content-Digest header is present it is verified
+// and requests that don't match are answered with 400 Bad Request. Handlers get the raw
+// body with RawBody and can still read r.Body as usual.
+func ContentDigestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxCallbackBodySize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		if header := r.Header.Get("Content-Digest"); header != "" {
+			if err := VerifyContentDigest(header, body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), rawBodyKey{}, body))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RawBody returns the request body buffered by ContentDigestMiddleware
+func RawBody(r *http.Request) ([]byte, bool) {
+	body, ok := r.Context().Value(rawBodyKey{}).([]byte)
+	return body, ok
+}
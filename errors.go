@@ -0,0 +1,166 @@
+package pawapaygo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for common failure categories. Errors returned by the Client
+// match them with errors.Is, e.g. errors.Is(err, ErrInvalidInput).
+var (
+	ErrAuthentication      = errors.New("pawapay: authentication failed")
+	ErrInvalidInput        = errors.New("pawapay: invalid input")
+	ErrNotAllowed          = errors.New("pawapay: operation not allowed")
+	ErrProviderUnavailable = errors.New("pawapay: provider temporarily unavailable")
+	ErrInsufficientBalance = errors.New("pawapay: insufficient balance")
+	ErrNotFound            = errors.New("pawapay: not found")
+	ErrRateLimited         = errors.New("pawapay: rate limited")
+	ErrServer              = errors.New("pawapay: server error")
+
+	// ErrInvalidSignature is returned by VerifySignature for callbacks that fail verification
+	ErrInvalidSignature = errors.New("pawapay: invalid signature")
+	// ErrStaleCallback is reported by WebhookHandler for callbacks signed longer ago than MaxSignatureAge
+	ErrStaleCallback = errors.New("pawapay: stale callback")
+)
+
+// failureCodeCategories maps pawaPay failure codes to their sentinel error
+var failureCodeCategories = map[string]error{
+	FAILURE_CODE_NO_AUTHENTICATION:                ErrAuthentication,
+	FAILURE_CODE_AUTHENTICATION_ERROR:             ErrAuthentication,
+	FAILURE_CODE_AUTHORISATION_ERROR:              ErrAuthentication,
+	FAILURE_CODE_HTTP_SIGNATURE_ERROR:             ErrAuthentication,
+	FAILURE_CODE_INVALID_INPUT:                    ErrInvalidInput,
+	FAILURE_CODE_MISSING_PARAMETER:                ErrInvalidInput,
+	FAILURE_CODE_UNSUPPORTED_PARAMETER:            ErrInvalidInput,
+	FAILURE_CODE_INVALID_PARAMETER:                ErrInvalidInput,
+	FAILURE_CODE_INVALID_AMOUNT:                   ErrInvalidInput,
+	FAILURE_CODE_AMOUNT_OUT_OF_BOUNDS:             ErrInvalidInput,
+	FAILURE_CODE_INVALID_PHONE_NUMBER:             ErrInvalidInput,
+	FAILURE_CODE_INVALID_CURRENCY:                 ErrInvalidInput,
+	FAILURE_CODE_INVALID_PROVIDER:                 ErrInvalidInput,
+	FAILURE_CODE_DUPLICATE_METADATA_FIELD:         ErrInvalidInput,
+	FAILURE_CODE_DEPOSITS_NOT_ALLOWED:             ErrNotAllowed,
+	FAILURE_CODE_PAYOUTS_NOT_ALLOWED:              ErrNotAllowed,
+	FAILURE_CODE_REFUNDS_NOT_ALLOWED:              ErrNotAllowed,
+	FAILURE_CODE_PROVIDER_TEMPORARILY_UNAVAILABLE: ErrProviderUnavailable,
+	FAILURE_CODE_INSUFFICIENT_BALANCE:             ErrInsufficientBalance,
+	FAILURE_CODE_DEPOSIT_NOT_FOUND:                ErrNotFound,
+}
+
+// APIError is returned when pawaPay answers with an HTTP error or rejects a request.
+// Use errors.As to inspect it.
+type APIError struct {
+	StatusCode     int    // HTTP status code of the response
+	FailureCode    string // pawaPay failure code, e.g. FAILURE_CODE_INVALID_PHONE_NUMBER (may be empty)
+	FailureMessage string // Human readable description of the failure
+	Path           string // Path of the request, e.g. "/v2/deposits"
+	RawBody        []byte // Unparsed response body
+
+	// Operation is set when pawaPay rejected an initiation request, e.g. "deposit"
+	Operation string
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Operation != "" && e.FailureCode != "":
+		return fmt.Sprintf("%s rejected: %s - %s", e.Operation, e.FailureCode, e.FailureMessage)
+	case e.Operation != "" && e.FailureMessage != "":
+		return fmt.Sprintf("%s rejected: %s", e.Operation, e.FailureMessage)
+	case e.Operation != "":
+		return fmt.Sprintf("%s rejected without a failure reason", e.Operation)
+	case e.FailureCode != "":
+		return fmt.Sprintf("pawapay API error (status %d): %s - %s", e.StatusCode, e.FailureCode, e.FailureMessage)
+	case e.FailureMessage != "":
+		return fmt.Sprintf("pawapay API error (status %d): %s", e.StatusCode, e.FailureMessage)
+	default:
+		return fmt.Sprintf("HTTP %d: %s", e.StatusCode, string(e.RawBody))
+	}
+}
+
+// Is reports whether the error belongs to the category of the target sentinel error
+func (e *APIError) Is(target error) bool {
+	if category, ok := failureCodeCategories[e.FailureCode]; ok {
+		return category == target
+	}
+
+	switch target {
+	case ErrAuthentication:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrInvalidInput:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// IsRejection reports whether err is an *APIError for an initiation request that pawaPay
+// answered with status REJECTED. The Initiate methods return the decoded response together
+// with such an error.
+func IsRejection(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Operation != ""
+}
+
+// decodeErrorResponse converts the body of a 4xx or 5xx response into an *APIError
+func decodeErrorResponse(statusCode int, path string, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Path:       path,
+		RawBody:    body,
+	}
+
+	// Rejections carry a failureReason, other errors use the generic ErrorResponse format
+	rejection := struct {
+		FailureReason *FailureReason `json:"failureReason"`
+	}{}
+	if err := json.Unmarshal(body, &rejection); err == nil && rejection.FailureReason != nil {
+		apiErr.FailureCode = rejection.FailureReason.FailureCode
+		apiErr.FailureMessage = rejection.FailureReason.FailureMessage
+		return apiErr
+	}
+
+	errResp := &ErrorResponse{}
+	if err := json.Unmarshal(body, errResp); err == nil {
+		apiErr.FailureMessage = errResp.message()
+		if errResp.Path != "" {
+			apiErr.Path = errResp.Path
+		}
+	}
+
+	return apiErr
+}
+
+// checkRejection returns an *APIError when a response reports the request as REJECTED, also
+// when it carries no failureReason
+func checkRejection(operation string, res *apiResponse) error {
+	rejection := struct {
+		Status        string         `json:"status"`
+		FailureReason *FailureReason `json:"failureReason"`
+	}{}
+	if err := json.Unmarshal(res.body, &rejection); err != nil {
+		return nil
+	}
+
+	if rejection.Status != string(INITIATION_STATUS_REJECTED) {
+		return nil
+	}
+
+	apiErr := &APIError{
+		StatusCode: res.statusCode,
+		Path:       res.path,
+		RawBody:    res.body,
+		Operation:  operation,
+	}
+	if rejection.FailureReason != nil {
+		apiErr.FailureCode = rejection.FailureReason.FailureCode
+		apiErr.FailureMessage = rejection.FailureReason.FailureMessage
+	}
+	return apiErr
+}
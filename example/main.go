@@ -37,6 +37,14 @@ func mainWebServer() {
 	fmt.Println("CONFIG Variables\n", cfg)
 	client := pawapay.NewPawapayClient(cfg)
 
+	// Parse the public key once, so a missing or malformed key stops the server at startup
+	// rather than on the first callback
+	publicKey, err := pawapay.ParsePublicKeyPEM([]byte(os.Getenv("PAWAPAY_PUBLIC_KEY")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys := pawapay.PublicKeys{os.Getenv("PAWAPAY_KEY_ID"): publicKey}
+
 	router := gin.Default()
 
 	router.POST("/initiate-deposit", func(c *gin.Context) {
@@ -63,24 +71,60 @@ func mainWebServer() {
 		}
 		c.JSON(http.StatusOK, res)
 	})
+	// Typed callback handling with signature verification
+	webhook := &pawapay.WebhookHandler{
+		Keys: pawapay.NewKeyStore(client),
+		OnDepositCompleted: func(ctx context.Context, callback *pawapay.DepositCallback) error {
+			fmt.Printf("Deposit %s completed\n", callback.DepositID)
+			return nil
+		},
+		OnDepositFailed: func(ctx context.Context, callback *pawapay.DepositCallback) error {
+			reason := "no failure reason"
+			if callback.FailureReason != nil {
+				reason = callback.FailureReason.FailureMessage
+			}
+			fmt.Printf("Deposit %s failed: %s\n", callback.DepositID, reason)
+			return nil
+		},
+		OnError: func(r *http.Request, err error) {
+			fmt.Println(err)
+		},
+	}
+	router.POST("/callbacks", gin.WrapH(webhook))
+
 	router.POST("/deposit-callback", func(c *gin.Context) {
 		// fmt.Println(c.Request)
-		body := &pawapay.DepositCallbackRequestBody{}
-		if err := c.ShouldBindBodyWith(body, binding.JSON); err != nil {
+		rawBody, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+		// Handles both v2 and legacy v1 deposit callbacks
+		body, err := pawapay.ParseDepositCallback(rawBody)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, err)
 			return
 		}
-		validated := pawapay.ValidateSignature(c.Request, "keyId", os.Getenv("PrivateKey"))
-		fmt.Println(validated)
+
+		if err := pawapay.VerifySignature(c.Request, rawBody, keys); err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"validated": validated,
+			"validated": true,
 		})
 
 		// Parse signature input parameters
-		for _, part := range strings.Split(c.Request.Header.Get("Signature-Input"), ";") {
-			kv := strings.Split(part, "=")
-			if len(kv) >= 2 {
-				fmt.Printf("Key: %s, Value: %s\n", kv[0], kv[1])
+		signatureInput, err := sfv.ParseDictionary(c.Request.Header.Get("Signature-Input"))
+		if err != nil {
+			fmt.Println(err)
+		}
+		for _, signature := range signatureInput {
+			if list, ok := signature.Value.(sfv.InnerList); ok {
+				for _, param := range list.Params {
+					fmt.Printf("Signature: %s, Key: %s, Value: %v\n", signature.Key, param.Key, param.Value)
+				}
 			}
 		}
 
@@ -150,15 +194,15 @@ func pawapayDepositExample() {
 		Metadata:             []pawapay.MetadataItem{},
 	}
 
-	// Initiate the deposit
+	// Initiate the deposit. A rejected deposit returns both the response and an error.
 	response, err := client.InitiateDeposit(depositRequest)
-	if err != nil {
+	if err != nil && !pawapay.IsRejection(err) {
 		log.Printf("Error initiating deposit: %v", err)
 		return
 	}
 
 	// Handle the response
-	fmt.Printf("Deposit initiated successfully!\n")
+	fmt.Printf("Deposit initiated!\n")
 	fmt.Printf("  Deposit ID: %s\n", response.DepositID)
 	fmt.Printf("  Status: %s\n", response.Status)
 	fmt.Printf("  Created: %s\n", response.Created)
@@ -173,11 +217,11 @@ func pawapayDepositExample() {
 	// - REJECTED: The deposit request was rejected
 	// - DUPLICATE_IGNORED: A deposit with the same ID already exists
 	switch response.Status {
-	case "ACCEPTED":
+	case pawapay.INITIATION_STATUS_ACCEPTED:
 		fmt.Println("✅ Deposit accepted! Waiting for customer to confirm on their phone.")
-	case "REJECTED":
+	case pawapay.INITIATION_STATUS_REJECTED:
 		fmt.Printf("❌ Deposit rejected: %s\n", response.FailureReason.FailureMessage)
-	case "DUPLICATE_IGNORED":
+	case pawapay.INITIATION_STATUS_DUPLICATE_IGNORED:
 		fmt.Println("⚠️ Duplicate deposit ignored - this deposit ID was already used.")
 	}
 }
@@ -318,7 +362,7 @@ func getDepositStatusExample() {
 	fmt.Printf("\n📊 Deposit Status Check:\n")
 	fmt.Printf("Status: %s\n", response.Status)
 
-	if response.Status == "FOUND" && response.Data != nil {
+	if response.Status.IsFound() && response.Data != nil {
 		fmt.Printf("\n✅ Deposit Found:\n")
 		fmt.Printf("Deposit ID: %s\n", response.Data.DepositID)
 		fmt.Printf("Status: %s\n", response.Data.Status)
@@ -365,22 +409,26 @@ func getDepositStatusExample() {
 		// Status interpretation
 		fmt.Printf("\n📌 Status Interpretation:\n")
 		switch response.Data.Status {
-		case "SUBMITTED":
+		case pawapay.DEPOSIT_STATUS_SUBMITTED:
 			fmt.Println("The deposit has been submitted to the provider")
-		case "ACCEPTED":
+		case pawapay.DEPOSIT_STATUS_ACCEPTED:
 			fmt.Println("The deposit has been accepted by the provider")
-		case "COMPLETED":
+		case pawapay.DEPOSIT_STATUS_PROCESSING:
+			fmt.Println("The provider is processing the deposit")
+		case pawapay.DEPOSIT_STATUS_IN_RECONCILIATION:
+			fmt.Println("The deposit is being reconciled and its final status will follow")
+		case pawapay.DEPOSIT_STATUS_COMPLETED:
 			fmt.Println("✅ The deposit has been completed successfully")
-		case "FAILED":
+		case pawapay.DEPOSIT_STATUS_FAILED:
 			fmt.Println("❌ The deposit has failed")
-		case "REJECTED":
+		case pawapay.DEPOSIT_STATUS_REJECTED:
 			fmt.Println("❌ The deposit was rejected")
-		case "ENQUEUED":
+		case pawapay.DEPOSIT_STATUS_ENQUEUED:
 			fmt.Println("The deposit is enqueued and waiting to be processed")
 		default:
 			fmt.Printf("Unknown status: %s\n", response.Data.Status)
 		}
-	} else if response.Status == "NOT_FOUND" {
+	} else if response.Status == pawapay.LOOKUP_STATUS_NOT_FOUND {
 		fmt.Printf("\n❌ Deposit Not Found\n")
 		fmt.Printf("The deposit with ID %s was not found in the system.\n", depositID)
 	}
@@ -36,5 +36,4 @@ require (
 require (
 	github.com/gin-gonic/gin v1.10.1
 	github.com/joho/godotenv v1.5.1
-	github.com/thinkgos/http-signature-go v0.3.1
 )
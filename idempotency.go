@@ -0,0 +1,212 @@
+package pawapaygo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idempotencyClaimTimeout is how long a claim blocks other deliveries of its key when it is
+// neither completed nor released
+const idempotencyClaimTimeout = 5 * time.Minute
+
+// IdempotencyStore records which callbacks have been processed, so callbacks that pawaPay
+// delivers more than once, or that are replayed, are only handled once. Keys combine the
+// callback type, ID and status, e.g. "deposit:8917c345-4791-4285-a416-62f24b6982db:COMPLETED".
+//
+// A key is claimed while its callback is processed and completed once that succeeded. Only
+// completed keys must be durable: a claim that is lost in a crash lets pawaPay's redelivery
+// be processed again. Claims that are never completed or released should expire, e.g. after
+// a few minutes, for the same reason; the stores of this package expire them after 5 minutes.
+type IdempotencyStore interface {
+	// Claim marks key as in progress and reports whether it was neither claimed nor completed before
+	Claim(ctx context.Context, key string) (bool, error)
+	// Complete records that the callback of a claimed key was processed
+	Complete(ctx context.Context, key string) error
+	// Release removes the claim of key, so a redelivery of a callback that failed to process is handled
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps keys in memory. Keys are lost on
+// restart and not shared between instances. It is safe for concurrent use.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	claimed map[string]time.Time
+	keys    map[string]time.Time // Completed keys
+}
+
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+// NewMemoryIdempotencyStore creates a MemoryIdempotencyStore that forgets keys after ttl.
+// A ttl of 0 keeps keys forever.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		claimed: make(map[string]time.Time),
+		keys:    make(map[string]time.Time),
+	}
+}
+
+// Claim marks key as in progress and reports whether it was new
+func (s *MemoryIdempotencyStore) Claim(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.ttl > 0 {
+		for k, claimed := range s.keys {
+			if now.Sub(claimed) > s.ttl {
+				delete(s.keys, k)
+			}
+		}
+	}
+
+	if _, ok := s.keys[key]; ok || isClaimed(s.claimed, key, now) {
+		return false, nil
+	}
+	s.claimed[key] = now
+	return true, nil
+}
+
+// Complete records key as processed
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claimed, key)
+	s.keys[key] = time.Now()
+	return nil
+}
+
+// Release removes the claim of key
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claimed, key)
+	return nil
+}
+
+// FileIdempotencyStore is an IdempotencyStore that persists completed keys in a local
+// append-only file, so processed callbacks are remembered across restarts. Claims are only
+// kept in memory, so a callback interrupted by a crash is processed again when pawaPay
+// redelivers it. It is safe for concurrent use within one process; the file must not be
+// shared between processes.
+type FileIdempotencyStore struct {
+	mu      sync.Mutex
+	file    *os.File
+	claimed map[string]time.Time
+	keys    map[string]bool // Completed keys
+}
+
+var _ IdempotencyStore = (*FileIdempotencyStore)(nil)
+
+// OpenFileIdempotencyStore opens or creates the store at path and loads the keys it contains
+func OpenFileIdempotencyStore(path string) (*FileIdempotencyStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency store: %w", err)
+	}
+
+	// Every line is a completed key
+	keys := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			keys[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read idempotency store: %w", err)
+	}
+
+	return &FileIdempotencyStore{file: file, claimed: make(map[string]time.Time), keys: keys}, nil
+}
+
+// Claim marks key as in progress and reports whether it was new
+func (s *FileIdempotencyStore) Claim(_ context.Context, key string) (bool, error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.keys[key] || isClaimed(s.claimed, key, now) {
+		return false, nil
+	}
+	s.claimed[key] = now
+	return true, nil
+}
+
+// Complete records key as processed. The key is synced to disk before Complete returns.
+func (s *FileIdempotencyStore) Complete(_ context.Context, key string) error {
+	if err := validateIdempotencyKey(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.keys[key] {
+		if err := s.append(key); err != nil {
+			return err
+		}
+		s.keys[key] = true
+	}
+	delete(s.claimed, key)
+	return nil
+}
+
+// Release removes the claim of key
+func (s *FileIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claimed, key)
+	return nil
+}
+
+// Close closes the underlying file
+func (s *FileIdempotencyStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileIdempotencyStore) append(line string) error {
+	if _, err := s.file.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write idempotency store: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync idempotency store: %w", err)
+	}
+	return nil
+}
+
+// isClaimed reports whether key has a claim that hasn't expired, and drops expired claims
+func isClaimed(claimed map[string]time.Time, key string, now time.Time) bool {
+	for k, at := range claimed {
+		if now.Sub(at) > idempotencyClaimTimeout {
+			delete(claimed, k)
+		}
+	}
+	_, ok := claimed[key]
+	return ok
+}
+
+func validateIdempotencyKey(key string) error {
+	if key == "" || strings.ContainsAny(key, "\r\n") {
+		return fmt.Errorf("invalid idempotency key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package pawapaygo
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKeyStoreTTL = 24 * time.Hour
+	// Minimum time between two refetches caused by unknown key IDs, so forged
+	// callbacks can't make the KeyStore hammer the public key endpoint
+	defaultKeyStoreRefetchInterval = time.Minute
+)
+
+// PublicKey parses Key, e.g. into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k PublicKeyResponse) PublicKey() (crypto.PublicKey, error) {
+	if strings.HasPrefix(strings.TrimSpace(k.Key), "-----BEGIN") {
+		return ParsePublicKeyPEM([]byte(k.Key))
+	}
+
+	der, err := base64.StdEncoding.DecodeString(k.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key %s: %w", k.ID, err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", k.ID, err)
+	}
+	return key, nil
+}
+
+// KeyStore caches pawaPay's public keys by key ID for callback verification. Keys are
+// refetched once TTL has passed, and when a callback names a key ID that isn't cached yet,
+// so key rotation on pawaPay's side doesn't break verification. It implements
+// PublicKeyResolver and is safe for concurrent use.
+type KeyStore struct {
+	// TTL is how long fetched keys are cached. Defaults to 24 hours.
+	TTL time.Duration
+	// RefetchInterval is the minimum time between two fetches caused by unknown key IDs, and
+	// between retries after a failed fetch. Defaults to 1 minute.
+	RefetchInterval time.Duration
+	// OnInvalidKey is called for every fetched key that can't be parsed. Such keys are skipped
+	// and the other keys are used.
+	OnInvalidKey func(keyID string, err error)
+
+	client PawapayAPIClient
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	failedAt  time.Time
+}
+
+var _ PublicKeyResolver = (*KeyStore)(nil)
+
+// NewKeyStore creates a KeyStore that fetches keys with client.GetPublicKeysContext
+func NewKeyStore(client PawapayAPIClient) *KeyStore {
+	return &KeyStore{client: client}
+}
+
+// PublicKey returns the key with the given ID, fetching keys from pawaPay when needed
+func (s *KeyStore) PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = defaultKeyStoreTTL
+	}
+	refetchInterval := s.RefetchInterval
+	if refetchInterval == 0 {
+		refetchInterval = defaultKeyStoreRefetchInterval
+	}
+
+	// Expired keys stay in use when pawaPay can't be reached. Failed fetches are retried after
+	// refetchInterval, so an outage doesn't make every callback wait for a fetch.
+	canFetch := time.Since(s.failedAt) >= refetchInterval
+	if (s.keys == nil || time.Since(s.fetchedAt) > ttl) && canFetch {
+		err := s.refresh(ctx)
+		if err != nil && s.keys == nil {
+			return nil, err
+		}
+		canFetch = err == nil
+	}
+	if s.keys == nil {
+		return nil, fmt.Errorf("no public keys available, fetching them failed less than %s ago", refetchInterval)
+	}
+
+	if key, ok := s.keys[keyID]; ok {
+		return key, nil
+	}
+
+	// pawaPay may have rotated its keys since the last fetch
+	if canFetch && time.Since(s.fetchedAt) >= refetchInterval {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := s.keys[keyID]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", keyID)
+}
+
+// Refresh fetches the current keys from pawaPay, replacing the cached ones
+func (s *KeyStore) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.refresh(ctx)
+}
+
+// refresh replaces the cached keys with the ones that parse. The cached keys are kept when
+// the fetch fails or no fetched key can be parsed.
+func (s *KeyStore) refresh(ctx context.Context) error {
+	response, err := s.client.GetPublicKeysContext(ctx)
+	if err != nil {
+		s.failedAt = time.Now()
+		return fmt.Errorf("failed to fetch public keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(response))
+	var errs []error
+	for _, k := range response {
+		key, err := k.PublicKey()
+		if err != nil {
+			errs = append(errs, err)
+			if s.OnInvalidKey != nil {
+				s.OnInvalidKey(k.ID, err)
+			}
+			continue
+		}
+		keys[k.ID] = key
+	}
+	if len(keys) == 0 {
+		s.failedAt = time.Now()
+		if len(errs) == 0 {
+			return errors.New("no public keys returned")
+		}
+		return fmt.Errorf("no usable public keys: %w", errors.Join(errs...))
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
@@ -3,16 +3,46 @@ package pawapaygo
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
+	"net/http"
 	"time"
 )
 
 type ConfigOptions struct {
 	InstanceURL string
 	ApiToken    string
+
+	// HTTPClient is used for all requests when set. Timeout and Transport are ignored in that case.
+	HTTPClient *http.Client
+	// Transport is used by the default http.Client, e.g. to configure proxies, TLS roots or instrumentation.
+	Transport http.RoundTripper
+	// Timeout of a single request made by the default http.Client. Defaults to 30 seconds.
+	Timeout time.Duration
+
+	// RetryPolicy enables automatic retries of failed requests, e.g. DefaultRetryPolicy(). Nil disables retries.
+	RetryPolicy *RetryPolicy
+
+	// Signer signs requests that carry a body, as required when SignedRequestsOnly is enabled
+	// for the account. Nil sends unsigned requests.
+	Signer *RequestSigner
+
+	// ValidateRequests runs the Validate method of deposit, payout and refund requests before
+	// they are sent, using the active configuration fetched once and cached for an hour
+	ValidateRequests bool
 }
 
+// DepositCallback is the body of a deposit callback sent by the v2 API. It has the same
+// fields as the DepositData returned by GetDepositStatus.
+type DepositCallback = DepositData
+
+// PayoutCallback is the body of a payout callback sent by the v2 API
+type PayoutCallback = PayoutData
+
+// RefundCallback is the body of a refund callback sent by the v2 API
+type RefundCallback = RefundData
+
+// DepositCallbackRequestBody is the body of a deposit callback sent by the v1 API, kept for
+// legacy accounts. Use ParseDepositCallback to handle both versions as a DepositCallback.
 type DepositCallbackRequestBody struct {
 	// A UUIDv4 based ID specified by you, that uniquely identifies the deposit.
 	DepositID string `json:"depositId"`
@@ -105,6 +135,46 @@ func (i *DepositCallbackRequestBody) ToBytes() (*bytes.Reader, error) {
 	return bytes.NewReader(b), nil
 }
 
+// ToV2 converts the v1 callback into the v2 DepositCallback model
+func (i *DepositCallbackRequestBody) ToV2() *DepositCallback {
+	amount := i.DepositedAmount
+	if amount == "" {
+		amount = i.RequestedAmount
+	}
+
+	callback := &DepositCallback{
+		DepositID: i.DepositID,
+		Status:    DepositStatus(i.Status),
+		Amount:    amount,
+		Currency:  i.Currency,
+		Country:   i.Country,
+		Payer: PayerDetails{
+			Type: i.Payer.Type,
+			AccountDetails: PayerAccountDetails{
+				PhoneNumber: i.Payer.Address.Value,
+				Provider:    i.Correspondent,
+			},
+		},
+		CustomerMessage: i.StatementDescription,
+		Created:         i.Created,
+	}
+
+	if i.FailureReason != nil {
+		callback.FailureReason = &FailureReason{
+			FailureCode:    i.FailureReason.FailureCode,
+			FailureMessage: i.FailureReason.FailureMessage,
+		}
+	}
+	if i.Metadata.OrderID != "" {
+		callback.Metadata = append(callback.Metadata, MetadataItem{"orderId": i.Metadata.OrderID})
+	}
+	if i.Metadata.CustomerID != "" {
+		callback.Metadata = append(callback.Metadata, MetadataItem{"customerId": i.Metadata.CustomerID})
+	}
+
+	return callback
+}
+
 // Request Deposit request body
 type InitiateDepositRequestBody struct {
 	DepositID            string         `json:"depositId"`
@@ -139,10 +209,10 @@ type MetadataItem map[string]any
 
 // Request Deposit response object
 type RequestDepositResponse struct {
-	DepositID     string        `json:"depositId"`
-	Status        string        `json:"status"`
-	Created       string        `json:"created"`
-	FailureReason FailureReason `json:"failureReason"`
+	DepositID     string           `json:"depositId"`
+	Status        InitiationStatus `json:"status"`
+	Created       string           `json:"created"`
+	FailureReason FailureReason    `json:"failureReason"`
 }
 
 // HTTP Error response from Pawapay API (for 4xx, 5xx errors)
@@ -154,8 +224,20 @@ type ErrorResponse struct {
 	Path      string `json:"path"`
 }
 
+// ToError converts the response into an *APIError
 func (e *ErrorResponse) ToError() error {
-	return fmt.Errorf("pawapay API error (status %d): %s - %s", e.Status, e.Error, e.Message)
+	return &APIError{
+		StatusCode:     e.Status,
+		FailureMessage: e.message(),
+		Path:           e.Path,
+	}
+}
+
+func (e *ErrorResponse) message() string {
+	if e.Error == "" {
+		return e.Message
+	}
+	return e.Error + " - " + e.Message
 }
 
 func (r *RequestDepositResponse) DecodeBytes(b io.Reader) error {
@@ -200,6 +282,12 @@ type SignatureConfiguration struct {
 	SignedCallbacks    bool `json:"signedCallbacks"`
 }
 
+// PublicKeyResponse represents a public key pawaPay signs callbacks with
+type PublicKeyResponse struct {
+	ID  string `json:"id"`  // Key ID, matches the keyid parameter of callback signatures
+	Key string `json:"key"` // Base64 encoded X.509 SubjectPublicKeyInfo (PEM is accepted too)
+}
+
 // CountryConfig represents a country configuration
 type CountryConfig struct {
 	Country     string            `json:"country"`     // ISO 3166-1 alpha-3 country code
@@ -265,14 +353,14 @@ type Instruction struct {
 
 // CheckDepositStatusResponse represents the response from checking deposit status
 type CheckDepositStatusResponse struct {
-	Status string       `json:"status"` // FOUND or NOT_FOUND
+	Status LookupStatus `json:"status"`
 	Data   *DepositData `json:"data,omitempty"`
 }
 
 // DepositData represents the detailed deposit information
 type DepositData struct {
 	DepositID             string         `json:"depositId"`
-	Status                string         `json:"status"` // SUBMITTED, ACCEPTED, COMPLETED, FAILED, REJECTED, ENQUEUED
+	Status                DepositStatus  `json:"status"`
 	Amount                string         `json:"amount"`
 	Currency              string         `json:"currency"`
 	Country               string         `json:"country"`
@@ -308,3 +396,179 @@ type PredictProviderResponse struct {
 	Provider    string `json:"provider"`    // Mobile money provider
 	PhoneNumber string `json:"phoneNumber"` // Correctly formatted phone number
 }
+
+// Request Payout request body
+type InitiatePayoutRequestBody struct {
+	PayoutID          string         `json:"payoutId"`
+	Recipient         Recipient      `json:"recipient"`
+	ClientReferenceID string         `json:"clientReferenceId,omitempty"`
+	CustomerMessage   string         `json:"customerMessage,omitempty"`
+	Amount            string         `json:"amount"`
+	Currency          string         `json:"currency"`
+	Metadata          []MetadataItem `json:"metadata,omitempty"`
+}
+
+func (i *InitiatePayoutRequestBody) ToBytes() (*bytes.Reader, error) {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// Recipient represents the customer receiving a payout
+type Recipient struct {
+	Type           string         `json:"type"` // MMO (Mobile Money Operator)
+	AccountDetails AccountDetails `json:"accountDetails"`
+}
+
+// Request Payout response object
+type RequestPayoutResponse struct {
+	PayoutID      string           `json:"payoutId"`
+	Status        InitiationStatus `json:"status"`
+	Created       string           `json:"created"`
+	FailureReason FailureReason    `json:"failureReason"`
+}
+
+// CheckPayoutStatusResponse represents the response from checking payout status
+type CheckPayoutStatusResponse struct {
+	Status LookupStatus `json:"status"`
+	Data   *PayoutData  `json:"data,omitempty"`
+}
+
+// PayoutData represents the detailed payout information
+type PayoutData struct {
+	PayoutID              string         `json:"payoutId"`
+	Status                PayoutStatus   `json:"status"`
+	Amount                string         `json:"amount"`
+	Currency              string         `json:"currency"`
+	Country               string         `json:"country"`
+	Recipient             Recipient      `json:"recipient"`
+	CustomerMessage       string         `json:"customerMessage,omitempty"`
+	ClientReferenceID     string         `json:"clientReferenceId,omitempty"`
+	Created               string         `json:"created"`
+	ProviderTransactionID string         `json:"providerTransactionId,omitempty"`
+	Metadata              []MetadataItem `json:"metadata,omitempty"`
+	FailureReason         *FailureReason `json:"failureReason,omitempty"`
+}
+
+// IsEnqueued reports whether the payout is waiting for a delayed provider to become available
+func (p *PayoutData) IsEnqueued() bool {
+	return p.Status == PAYOUT_STATUS_ENQUEUED
+}
+
+// CanBeCancelled reports whether the payout can still be cancelled with CancelEnqueuedPayout
+func (p *PayoutData) CanBeCancelled() bool {
+	return p.IsEnqueued()
+}
+
+// CancelEnqueuedPayoutResponse represents the response from cancelling an enqueued payout
+type CancelEnqueuedPayoutResponse struct {
+	PayoutID      string           `json:"payoutId"`
+	Status        InitiationStatus `json:"status"` // ACCEPTED or REJECTED
+	FailureReason *FailureReason   `json:"failureReason,omitempty"`
+}
+
+// Request Refund request body
+type InitiateRefundRequestBody struct {
+	RefundID          string         `json:"refundId"`
+	DepositID         string         `json:"depositId"`
+	Amount            string         `json:"amount,omitempty"`   // Optional, omit to refund the full deposit amount
+	Currency          string         `json:"currency,omitempty"` // Required when Amount is set
+	ClientReferenceID string         `json:"clientReferenceId,omitempty"`
+	Metadata          []MetadataItem `json:"metadata,omitempty"`
+}
+
+func (i *InitiateRefundRequestBody) ToBytes() (*bytes.Reader, error) {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// Request Refund response object
+type RequestRefundResponse struct {
+	RefundID      string           `json:"refundId"`
+	Status        InitiationStatus `json:"status"`
+	Created       string           `json:"created"`
+	FailureReason FailureReason    `json:"failureReason"`
+}
+
+// CheckRefundStatusResponse represents the response from checking refund status
+type CheckRefundStatusResponse struct {
+	Status LookupStatus `json:"status"`
+	Data   *RefundData  `json:"data,omitempty"`
+}
+
+// RefundData represents the detailed refund information
+type RefundData struct {
+	RefundID              string         `json:"refundId"`
+	DepositID             string         `json:"depositId"`
+	Status                RefundStatus   `json:"status"`
+	Amount                string         `json:"amount"`
+	Currency              string         `json:"currency"`
+	Country               string         `json:"country"`
+	Recipient             Recipient      `json:"recipient"`
+	CustomerMessage       string         `json:"customerMessage,omitempty"`
+	ClientReferenceID     string         `json:"clientReferenceId,omitempty"`
+	Created               string         `json:"created"`
+	ProviderTransactionID string         `json:"providerTransactionId,omitempty"`
+	Metadata              []MetadataItem `json:"metadata,omitempty"`
+	FailureReason         *FailureReason `json:"failureReason,omitempty"`
+}
+
+// PaymentPageRequestBody represents the request body for creating a hosted Payment Page session
+type PaymentPageRequestBody struct {
+	DepositID       string         `json:"depositId"`
+	ReturnURL       string         `json:"returnUrl"` // Where the customer is redirected once the payment is done
+	CustomerMessage string         `json:"customerMessage,omitempty"`
+	AmountDetails   *AmountDetails `json:"amountDetails,omitempty"` // Optional, the customer enters the amount when omitted
+	PhoneNumber     string         `json:"phoneNumber,omitempty"`   // Optional, pre-fills the customer's phone number
+	Language        string         `json:"language,omitempty"`      // EN or FR
+	Country         string         `json:"country,omitempty"`       // ISO 3166-1 alpha-3 country code
+	Reason          string         `json:"reason,omitempty"`        // Shown to the customer on the Payment Page
+	Metadata        []MetadataItem `json:"metadata,omitempty"`
+}
+
+func (i *PaymentPageRequestBody) ToBytes() (*bytes.Reader, error) {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// AmountDetails represents the amount and currency to collect through the Payment Page
+type AmountDetails struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// PaymentPageResponse represents the response from creating a Payment Page session
+type PaymentPageResponse struct {
+	RedirectURL   string           `json:"redirectUrl"`
+	Status        InitiationStatus `json:"status,omitempty"` // Only set when the session is REJECTED
+	FailureReason *FailureReason   `json:"failureReason,omitempty"`
+}
+
+// ResendDepositCallbackResponse represents the response from resending a deposit callback
+type ResendDepositCallbackResponse struct {
+	DepositID     string           `json:"depositId"`
+	Status        InitiationStatus `json:"status"` // ACCEPTED or REJECTED
+	FailureReason *FailureReason   `json:"failureReason,omitempty"`
+}
+
+// ResendPayoutCallbackResponse represents the response from resending a payout callback
+type ResendPayoutCallbackResponse struct {
+	PayoutID      string           `json:"payoutId"`
+	Status        InitiationStatus `json:"status"` // ACCEPTED or REJECTED
+	FailureReason *FailureReason   `json:"failureReason,omitempty"`
+}
+
+// ResendRefundCallbackResponse represents the response from resending a refund callback
+type ResendRefundCallbackResponse struct {
+	RefundID      string           `json:"refundId"`
+	Status        InitiationStatus `json:"status"` // ACCEPTED or REJECTED
+	FailureReason *FailureReason   `json:"failureReason,omitempty"`
+}
@@ -1,27 +1,40 @@
 package pawapaygo
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha512"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
-	hs "github.com/thinkgos/http-signature-go"
+	"github.com/salticon/pawapay-go-sdk/sfv"
 )
 
 type Client struct {
 	instanceURL string
 	authToken   string
+	httpClient  *http.Client
+	retryPolicy *RetryPolicy
+	signer      *RequestSigner
 	Debug       bool
+
+	// Active configuration cached for request validation
+	validateRequests bool
+	configMu         sync.Mutex
+	config           *ActiveConfigurationResponse
+	configFetchedAt  time.Time
+	configFailedAt   time.Time
 }
 
 var _ PawapayAPIClient = (*Client)(nil)
 
-const defaultBaseURL = "https://api.pawapay.io"
+const (
+	defaultBaseURL = "https://api.pawapay.io"
+	defaultTimeout = 30 * time.Second
+)
 
 func NewPawapayClient(cfg *ConfigOptions) *Client {
 	baseURL := cfg.InstanceURL
@@ -32,444 +45,507 @@ func NewPawapayClient(cfg *ConfigOptions) *Client {
 	return &Client{
 		instanceURL: baseURL,
 		authToken:   cfg.ApiToken,
+		httpClient:  newHTTPClient(cfg),
+		retryPolicy: cfg.RetryPolicy,
+		signer:      cfg.Signer,
+
+		validateRequests: cfg.ValidateRequests,
+	}
+}
+
+// newHTTPClient returns the http.Client shared by all requests of a Client.
+// A caller supplied HTTPClient is used as is, otherwise a client with a pooled
+// transport and the configured timeout is created.
+func newHTTPClient(cfg *ConfigOptions) *http.Client {
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		// http.DefaultTransport may have been replaced, e.g. by httpmock or instrumentation,
+		// in which case it is used as is
+		transport = http.DefaultTransport
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			t = t.Clone()
+			t.MaxIdleConnsPerHost = 10
+			transport = t
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
 	}
 }
 
 type PawapayAPIClient interface {
 	InitiateDeposit(*InitiateDepositRequestBody) (*RequestDepositResponse, error)
+	InitiateDepositContext(context.Context, *InitiateDepositRequestBody) (*RequestDepositResponse, error)
 	GetWalletBalances() (*WalletBalancesResponse, error)
+	GetWalletBalancesContext(ctx context.Context) (*WalletBalancesResponse, error)
 	GetActiveConfiguration() (*ActiveConfigurationResponse, error)
+	GetActiveConfigurationContext(ctx context.Context) (*ActiveConfigurationResponse, error)
 	GetDepositStatus(depositID string) (*CheckDepositStatusResponse, error)
+	GetDepositStatusContext(ctx context.Context, depositID string) (*CheckDepositStatusResponse, error)
 	PredictProvider(phoneNumber string) (*PredictProviderResponse, error)
+	PredictProviderContext(ctx context.Context, phoneNumber string) (*PredictProviderResponse, error)
+	InitiatePayout(*InitiatePayoutRequestBody) (*RequestPayoutResponse, error)
+	InitiatePayoutContext(context.Context, *InitiatePayoutRequestBody) (*RequestPayoutResponse, error)
+	GetPayoutStatus(payoutID string) (*CheckPayoutStatusResponse, error)
+	GetPayoutStatusContext(ctx context.Context, payoutID string) (*CheckPayoutStatusResponse, error)
+	InitiateBulkPayout(payouts []InitiatePayoutRequestBody) ([]RequestPayoutResponse, error)
+	InitiateBulkPayoutContext(ctx context.Context, payouts []InitiatePayoutRequestBody) ([]RequestPayoutResponse, error)
+	InitiateRefund(*InitiateRefundRequestBody) (*RequestRefundResponse, error)
+	InitiateRefundContext(context.Context, *InitiateRefundRequestBody) (*RequestRefundResponse, error)
+	GetRefundStatus(refundID string) (*CheckRefundStatusResponse, error)
+	GetRefundStatusContext(ctx context.Context, refundID string) (*CheckRefundStatusResponse, error)
+	CreatePaymentPageSession(*PaymentPageRequestBody) (*PaymentPageResponse, error)
+	CreatePaymentPageSessionContext(context.Context, *PaymentPageRequestBody) (*PaymentPageResponse, error)
+	ResendDepositCallback(depositID string) (*ResendDepositCallbackResponse, error)
+	ResendDepositCallbackContext(ctx context.Context, depositID string) (*ResendDepositCallbackResponse, error)
+	ResendPayoutCallback(payoutID string) (*ResendPayoutCallbackResponse, error)
+	ResendPayoutCallbackContext(ctx context.Context, payoutID string) (*ResendPayoutCallbackResponse, error)
+	ResendRefundCallback(refundID string) (*ResendRefundCallbackResponse, error)
+	ResendRefundCallbackContext(ctx context.Context, refundID string) (*ResendRefundCallbackResponse, error)
+	CancelEnqueuedPayout(payoutID string) (*CancelEnqueuedPayoutResponse, error)
+	CancelEnqueuedPayoutContext(ctx context.Context, payoutID string) (*CancelEnqueuedPayoutResponse, error)
+	GetPublicKeys() ([]PublicKeyResponse, error)
+	GetPublicKeysContext(ctx context.Context) ([]PublicKeyResponse, error)
 }
 
+// InitiateDeposit initiates a mobile money deposit request.
+// When pawaPay rejects the deposit, both the response and an *APIError are returned so the
+// rejection can be recorded together with its FailureReason.
 func (a *Client) InitiateDeposit(payload *InitiateDepositRequestBody) (*RequestDepositResponse, error) {
+	return a.InitiateDepositContext(context.Background(), payload)
+}
 
-	// Initialize an http client
-	httpc := http.Client{}
-
-	requestBody, err := payload.ToBytes()
-	if err != nil {
-		fmt.Println("Error converting request body to bytes\n", err)
+// InitiateDepositContext is like InitiateDeposit but uses ctx for the underlying HTTP request
+func (a *Client) InitiateDepositContext(ctx context.Context, payload *InitiateDepositRequestBody) (*RequestDepositResponse, error) {
+	if err := a.validate(ctx, payload); err != nil {
 		return nil, err
 	}
 
-	// Build the URL, ensuring no double slashes
-	baseURL := strings.TrimSuffix(a.instanceURL, "/")
-	url := baseURL + "/v2" + requestDepositRoute
-
-	// Create an http request
-	req, err := http.NewRequest("POST", url, requestBody)
-	if err != nil {
-		fmt.Println("Error creating new request body\n", err)
+	body := &RequestDepositResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: requestDepositRoute, body: payload, operation: "deposit"}, body); err != nil {
+		if IsRejection(err) {
+			return body, err
+		}
 		return nil, err
 	}
 
-	// Add required http headers
-	req.Header.Set("Authorization", "Bearer "+a.authToken)
-	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	return body, nil
+}
 
-	// Debug logging for request body
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: REQUEST ==========")
-		fmt.Printf("URL: %s\n", url)
+// GetWalletBalances retrieves the list of wallets and their balances configured for your pawaPay account
+func (a *Client) GetWalletBalances() (*WalletBalancesResponse, error) {
+	return a.GetWalletBalancesContext(context.Background())
+}
 
-		// Mask the token for security (show first 8 chars only)
-		maskedToken := a.authToken
-		if len(maskedToken) > 8 {
-			maskedToken = maskedToken[:8] + "..." + maskedToken[len(maskedToken)-4:]
-		}
-		fmt.Printf("Authorization: Bearer %s\n", maskedToken)
-		fmt.Printf("Content-Type: %s\n", req.Header.Get("Content-Type"))
-
-		fmt.Println("Body:")
-		// Read the request body for logging
-		if req.Body != nil {
-			bodyBytes, _ := io.ReadAll(req.Body)
-			fmt.Println(string(bodyBytes))
-			// Restore the body for the actual request
-			req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		}
-		fmt.Println("====================================")
-	}
+// GetWalletBalancesContext is like GetWalletBalances but uses ctx for the underlying HTTP request
+func (a *Client) GetWalletBalancesContext(ctx context.Context) (*WalletBalancesResponse, error) {
+	const walletBalancesRoute = "/wallet-balances"
 
-	res, err := httpc.Do(req)
-	if err != nil {
-		fmt.Println("Error making an http request to pawapay\n", err)
+	body := &WalletBalancesResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodGet, path: walletBalancesRoute}, body); err != nil {
 		return nil, err
 	}
-	// Close request body stream in the end
-	defer res.Body.Close()
 
-	// Read response body
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("Error reading response body\n", err)
-		return nil, err
-	}
+	return body, nil
+}
 
-	// Debug logging for response body
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: RESPONSE ==========")
-		fmt.Printf("Status: %d %s\n", res.StatusCode, res.Status)
-		fmt.Println("Body:")
-		fmt.Println(string(resBody))
-		fmt.Println("=====================================")
-	}
+// GetActiveConfiguration retrieves the active configuration including countries, providers, and operation types
+func (a *Client) GetActiveConfiguration() (*ActiveConfigurationResponse, error) {
+	return a.GetActiveConfigurationContext(context.Background())
+}
 
-	// Parse the response body
-	body := &RequestDepositResponse{}
-	if err := body.DecodeBytes(bytes.NewReader(resBody)); err != nil {
-		// If we can't parse as RequestDepositResponse, try parsing as HTTP error
-		if res.StatusCode >= 400 {
-			errResp := &ErrorResponse{}
-			if err := json.Unmarshal(resBody, errResp); err != nil {
-				// If we can't parse the error response, return a generic error
-				return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode, string(resBody))
-			}
-			return nil, errResp.ToError()
-		}
-		fmt.Println("Error parsing the response body to go struct\n", err)
-		return nil, err
-	}
+// GetActiveConfigurationContext is like GetActiveConfiguration but uses ctx for the underlying HTTP request
+func (a *Client) GetActiveConfigurationContext(ctx context.Context) (*ActiveConfigurationResponse, error) {
+	const activeConfRoute = "/active-conf"
 
-	// Check if the response indicates a rejection with failure reason
-	if body.Status == "REJECTED" && body.FailureReason.FailureCode != "" {
-		return nil, fmt.Errorf("deposit rejected: %s - %s", body.FailureReason.FailureCode, body.FailureReason.FailureMessage)
+	body := &ActiveConfigurationResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodGet, path: activeConfRoute}, body); err != nil {
+		return nil, err
 	}
 
 	return body, nil
 }
 
-// GetWalletBalances retrieves the list of wallets and their balances configured for your pawaPay account
-func (a *Client) GetWalletBalances() (*WalletBalancesResponse, error) {
-	const walletBalancesRoute = "/wallet-balances"
-
-	httpc := &http.Client{}
+// GetDepositStatus retrieves the current status of a deposit based on its depositId
+func (a *Client) GetDepositStatus(depositID string) (*CheckDepositStatusResponse, error) {
+	return a.GetDepositStatusContext(context.Background(), depositID)
+}
 
-	// Build the URL, ensuring no double slashes
-	baseURL := strings.TrimSuffix(a.instanceURL, "/")
-	url := baseURL + "/v2" + walletBalancesRoute
+// GetDepositStatusContext is like GetDepositStatus but uses ctx for the underlying HTTP request
+func (a *Client) GetDepositStatusContext(ctx context.Context, depositID string) (*CheckDepositStatusResponse, error) {
+	if depositID == "" {
+		return nil, fmt.Errorf("depositID is required")
+	}
 
-	// Create an http request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("Error creating new request\n", err)
+	body := &CheckDepositStatusResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodGet, path: requestDepositRoute + "/" + url.PathEscape(depositID)}, body); err != nil {
 		return nil, err
 	}
 
-	// Add required http headers
-	req.Header.Set("Authorization", "Bearer "+a.authToken)
-
-	// Debug logging for request
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: REQUEST ==========")
-		fmt.Printf("URL: %s\n", url)
+	return body, nil
+}
 
-		// Mask the token for security (show first 8 chars only)
-		maskedToken := a.authToken
-		if len(maskedToken) > 8 {
-			maskedToken = maskedToken[:8] + "..." + maskedToken[len(maskedToken)-4:]
-		}
-		fmt.Printf("Authorization: Bearer %s\n", maskedToken)
-		fmt.Println("====================================")
-	}
+// InitiatePayout sends money from your wallet to a customer's mobile money account.
+// When pawaPay rejects the payout, both the response and an *APIError are returned.
+func (a *Client) InitiatePayout(payload *InitiatePayoutRequestBody) (*RequestPayoutResponse, error) {
+	return a.InitiatePayoutContext(context.Background(), payload)
+}
 
-	res, err := httpc.Do(req)
-	if err != nil {
-		fmt.Println("Error making an http request to pawapay\n", err)
+// InitiatePayoutContext is like InitiatePayout but uses ctx for the underlying HTTP request
+func (a *Client) InitiatePayoutContext(ctx context.Context, payload *InitiatePayoutRequestBody) (*RequestPayoutResponse, error) {
+	if err := a.validate(ctx, payload); err != nil {
 		return nil, err
 	}
-	// Close request body stream in the end
-	defer res.Body.Close()
 
-	// Read response body
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("Error reading response body\n", err)
+	body := &RequestPayoutResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: requestPayoutRoute, body: payload, operation: "payout"}, body); err != nil {
+		if IsRejection(err) {
+			return body, err
+		}
 		return nil, err
 	}
 
-	// Debug logging for response body
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: RESPONSE ==========")
-		fmt.Printf("Status: %d %s\n", res.StatusCode, res.Status)
-		fmt.Println("Body:")
-		fmt.Println(string(resBody))
-		fmt.Println("=====================================")
+	return body, nil
+}
+
+// InitiateBulkPayout sends multiple payouts, splitting them into batches of at most
+// MaxBulkPayoutSize. The returned slice holds one result per submitted payout in the
+// same order. Rejected payouts are reported through their Status and FailureReason
+// rather than as an error. If a batch fails, the results of the batches already
+// submitted are returned together with the error.
+func (a *Client) InitiateBulkPayout(payouts []InitiatePayoutRequestBody) ([]RequestPayoutResponse, error) {
+	return a.InitiateBulkPayoutContext(context.Background(), payouts)
+}
+
+// InitiateBulkPayoutContext is like InitiateBulkPayout but uses ctx for the underlying HTTP request
+func (a *Client) InitiateBulkPayoutContext(ctx context.Context, payouts []InitiatePayoutRequestBody) ([]RequestPayoutResponse, error) {
+	if len(payouts) == 0 {
+		return nil, fmt.Errorf("at least one payout is required")
+	}
+	for i := range payouts {
+		if err := a.validate(ctx, &payouts[i]); err != nil {
+			return nil, fmt.Errorf("payout %d: %w", i, err)
+		}
 	}
 
-	// Check if response is an HTTP error (4xx, 5xx)
-	if res.StatusCode >= 400 {
-		errResp := &ErrorResponse{}
-		if err := json.Unmarshal(resBody, errResp); err != nil {
-			// If we can't parse the error response, return a generic error
-			return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode, string(resBody))
+	results := make([]RequestPayoutResponse, 0, len(payouts))
+	for start := 0; start < len(payouts); start += MaxBulkPayoutSize {
+		end := min(start+MaxBulkPayoutSize, len(payouts))
+
+		batchResults, err := a.initiateBulkPayoutBatch(ctx, payouts[start:end])
+		if err != nil {
+			return results, fmt.Errorf("bulk payout batch %d-%d failed: %w", start, end-1, err)
 		}
-		return nil, errResp.ToError()
+		results = append(results, batchResults...)
 	}
 
-	// Parse the response body
-	body := &WalletBalancesResponse{}
-	if err := json.Unmarshal(resBody, body); err != nil {
-		fmt.Println("Error parsing the response body to go struct\n", err)
+	return results, nil
+}
+
+func (a *Client) initiateBulkPayoutBatch(ctx context.Context, batch []InitiatePayoutRequestBody) ([]RequestPayoutResponse, error) {
+	var body bulkPayoutResponses
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: requestPayoutRoute + "/bulk", body: batch}, &body); err != nil {
 		return nil, err
 	}
 
+	if len(body) != len(batch) {
+		return nil, fmt.Errorf("expected %d bulk payout results, got %d", len(batch), len(body))
+	}
+
 	return body, nil
 }
 
-// GetActiveConfiguration retrieves the active configuration including countries, providers, and operation types
-func (a *Client) GetActiveConfiguration() (*ActiveConfigurationResponse, error) {
-	const activeConfRoute = "/active-conf"
-
-	httpc := &http.Client{}
+// GetPayoutStatus retrieves the current status of a payout based on its payoutId
+func (a *Client) GetPayoutStatus(payoutID string) (*CheckPayoutStatusResponse, error) {
+	return a.GetPayoutStatusContext(context.Background(), payoutID)
+}
 
-	// Build the URL, ensuring no double slashes
-	baseURL := strings.TrimSuffix(a.instanceURL, "/")
-	url := baseURL + "/v2" + activeConfRoute
+// GetPayoutStatusContext is like GetPayoutStatus but uses ctx for the underlying HTTP request
+func (a *Client) GetPayoutStatusContext(ctx context.Context, payoutID string) (*CheckPayoutStatusResponse, error) {
+	if payoutID == "" {
+		return nil, fmt.Errorf("payoutID is required")
+	}
 
-	// Create an http request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("Error creating new request\n", err)
+	body := &CheckPayoutStatusResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodGet, path: requestPayoutRoute + "/" + url.PathEscape(payoutID)}, body); err != nil {
 		return nil, err
 	}
 
-	// Add required http headers
-	req.Header.Set("Authorization", "Bearer "+a.authToken)
+	return body, nil
+}
 
-	// Debug logging for request
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: REQUEST ==========")
-		fmt.Printf("URL: %s\n", url)
+// InitiateRefund refunds a previously completed deposit, either fully or partially.
+// When pawaPay rejects the refund, both the response and an *APIError are returned.
+func (a *Client) InitiateRefund(payload *InitiateRefundRequestBody) (*RequestRefundResponse, error) {
+	return a.InitiateRefundContext(context.Background(), payload)
+}
 
-		// Mask the token for security (show first 8 chars only)
-		maskedToken := a.authToken
-		if len(maskedToken) > 8 {
-			maskedToken = maskedToken[:8] + "..." + maskedToken[len(maskedToken)-4:]
-		}
-		fmt.Printf("Authorization: Bearer %s\n", maskedToken)
-		fmt.Println("====================================")
+// InitiateRefundContext is like InitiateRefund but uses ctx for the underlying HTTP request
+func (a *Client) InitiateRefundContext(ctx context.Context, payload *InitiateRefundRequestBody) (*RequestRefundResponse, error) {
+	if payload.DepositID == "" {
+		return nil, fmt.Errorf("depositID is required")
 	}
-
-	res, err := httpc.Do(req)
-	if err != nil {
-		fmt.Println("Error making an http request to pawapay\n", err)
+	if err := a.validate(ctx, payload); err != nil {
 		return nil, err
 	}
-	// Close request body stream in the end
-	defer res.Body.Close()
 
-	// Read response body
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("Error reading response body\n", err)
+	body := &RequestRefundResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: requestRefundRoute, body: payload, operation: "refund"}, body); err != nil {
+		if IsRejection(err) {
+			return body, err
+		}
 		return nil, err
 	}
 
-	// Debug logging for response body
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: RESPONSE ==========")
-		fmt.Printf("Status: %d %s\n", res.StatusCode, res.Status)
-		fmt.Println("Body:")
-		fmt.Println(string(resBody))
-		fmt.Println("=====================================")
-	}
+	return body, nil
+}
 
-	// Check if response is an HTTP error (4xx, 5xx)
-	if res.StatusCode >= 400 {
-		errResp := &ErrorResponse{}
-		if err := json.Unmarshal(resBody, errResp); err != nil {
-			// If we can't parse the error response, return a generic error
-			return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode, string(resBody))
-		}
-		return nil, errResp.ToError()
+// GetRefundStatus retrieves the current status of a refund based on its refundId
+func (a *Client) GetRefundStatus(refundID string) (*CheckRefundStatusResponse, error) {
+	return a.GetRefundStatusContext(context.Background(), refundID)
+}
+
+// GetRefundStatusContext is like GetRefundStatus but uses ctx for the underlying HTTP request
+func (a *Client) GetRefundStatusContext(ctx context.Context, refundID string) (*CheckRefundStatusResponse, error) {
+	if refundID == "" {
+		return nil, fmt.Errorf("refundID is required")
 	}
 
-	// Parse the response body
-	body := &ActiveConfigurationResponse{}
-	if err := json.Unmarshal(resBody, body); err != nil {
-		fmt.Println("Error parsing the response body to go struct\n", err)
+	body := &CheckRefundStatusResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodGet, path: requestRefundRoute + "/" + url.PathEscape(refundID)}, body); err != nil {
 		return nil, err
 	}
 
 	return body, nil
 }
 
-// GetDepositStatus retrieves the current status of a deposit based on its depositId
-func (a *Client) GetDepositStatus(depositID string) (*CheckDepositStatusResponse, error) {
-	if depositID == "" {
+// CreatePaymentPageSession creates a hosted Payment Page session and returns the URL to
+// redirect the customer to. The deposit created by the session uses the supplied
+// depositId, so its outcome can be checked with GetDepositStatus.
+func (a *Client) CreatePaymentPageSession(payload *PaymentPageRequestBody) (*PaymentPageResponse, error) {
+	return a.CreatePaymentPageSessionContext(context.Background(), payload)
+}
+
+// CreatePaymentPageSessionContext is like CreatePaymentPageSession but uses ctx for the underlying HTTP request
+func (a *Client) CreatePaymentPageSessionContext(ctx context.Context, payload *PaymentPageRequestBody) (*PaymentPageResponse, error) {
+	if payload.DepositID == "" {
 		return nil, fmt.Errorf("depositID is required")
 	}
+	if payload.ReturnURL == "" {
+		return nil, fmt.Errorf("returnUrl is required")
+	}
 
-	httpc := &http.Client{}
-
-	// Build the URL, ensuring no double slashes
-	baseURL := strings.TrimSuffix(a.instanceURL, "/")
-	url := fmt.Sprintf("%s/v2/deposits/%s", baseURL, depositID)
-
-	// Create an http request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		fmt.Println("Error creating new request\n", err)
+	body := &PaymentPageResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: paymentPageRoute, body: payload, operation: "payment page session"}, body); err != nil {
+		if IsRejection(err) {
+			return body, err
+		}
 		return nil, err
 	}
 
-	// Add required http headers
-	req.Header.Set("Authorization", "Bearer "+a.authToken)
+	return body, nil
+}
 
-	// Debug logging for request
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: REQUEST ==========")
-		fmt.Printf("URL: %s\n", url)
+// CancelEnqueuedPayout fails a payout that is still in the ENQUEUED state so it will not be processed.
+// Use PayoutData.CanBeCancelled to check whether cancellation is allowed. A REJECTED status is
+// returned in the response rather than as an error.
+func (a *Client) CancelEnqueuedPayout(payoutID string) (*CancelEnqueuedPayoutResponse, error) {
+	return a.CancelEnqueuedPayoutContext(context.Background(), payoutID)
+}
 
-		// Mask the token for security (show first 8 chars only)
-		maskedToken := a.authToken
-		if len(maskedToken) > 8 {
-			maskedToken = maskedToken[:8] + "..." + maskedToken[len(maskedToken)-4:]
-		}
-		fmt.Printf("Authorization: Bearer %s\n", maskedToken)
-		fmt.Println("====================================")
+// CancelEnqueuedPayoutContext is like CancelEnqueuedPayout but uses ctx for the underlying HTTP request
+func (a *Client) CancelEnqueuedPayoutContext(ctx context.Context, payoutID string) (*CancelEnqueuedPayoutResponse, error) {
+	if payoutID == "" {
+		return nil, fmt.Errorf("payoutID is required")
 	}
 
-	res, err := httpc.Do(req)
-	if err != nil {
-		fmt.Println("Error making an http request to pawapay\n", err)
+	body := &CancelEnqueuedPayoutResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: requestPayoutRoute + "/fail-enqueued/" + url.PathEscape(payoutID)}, body); err != nil {
 		return nil, err
 	}
-	// Close request body stream in the end
-	defer res.Body.Close()
 
-	// Read response body
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("Error reading response body\n", err)
-		return nil, err
+	return body, nil
+}
+
+// ResendDepositCallback asks pawaPay to resend the callback for a deposit in a final state.
+// A REJECTED status is returned in the response rather than as an error.
+func (a *Client) ResendDepositCallback(depositID string) (*ResendDepositCallbackResponse, error) {
+	return a.ResendDepositCallbackContext(context.Background(), depositID)
+}
+
+// ResendDepositCallbackContext is like ResendDepositCallback but uses ctx for the underlying HTTP request
+func (a *Client) ResendDepositCallbackContext(ctx context.Context, depositID string) (*ResendDepositCallbackResponse, error) {
+	if depositID == "" {
+		return nil, fmt.Errorf("depositID is required")
 	}
 
-	// Debug logging for response body
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: RESPONSE ==========")
-		fmt.Printf("Status: %d %s\n", res.StatusCode, res.Status)
-		fmt.Println("Body:")
-		fmt.Println(string(resBody))
-		fmt.Println("=====================================")
+	body := &ResendDepositCallbackResponse{}
+	if err := a.resendCallback(ctx, requestDepositRoute, depositID, body); err != nil {
+		return nil, err
 	}
+	return body, nil
+}
 
-	// Check if response is an HTTP error (4xx, 5xx)
-	if res.StatusCode >= 400 {
-		errResp := &ErrorResponse{}
-		if err := json.Unmarshal(resBody, errResp); err != nil {
-			// If we can't parse the error response, return a generic error
-			return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode, string(resBody))
-		}
-		return nil, errResp.ToError()
+// ResendPayoutCallback asks pawaPay to resend the callback for a payout in a final state.
+// A REJECTED status is returned in the response rather than as an error.
+func (a *Client) ResendPayoutCallback(payoutID string) (*ResendPayoutCallbackResponse, error) {
+	return a.ResendPayoutCallbackContext(context.Background(), payoutID)
+}
+
+// ResendPayoutCallbackContext is like ResendPayoutCallback but uses ctx for the underlying HTTP request
+func (a *Client) ResendPayoutCallbackContext(ctx context.Context, payoutID string) (*ResendPayoutCallbackResponse, error) {
+	if payoutID == "" {
+		return nil, fmt.Errorf("payoutID is required")
 	}
 
-	// Parse the response body
-	body := &CheckDepositStatusResponse{}
-	if err := json.Unmarshal(resBody, body); err != nil {
-		fmt.Println("Error parsing the response body to go struct\n", err)
+	body := &ResendPayoutCallbackResponse{}
+	if err := a.resendCallback(ctx, requestPayoutRoute, payoutID, body); err != nil {
 		return nil, err
 	}
-
 	return body, nil
 }
 
-func ValidateSignature(r *http.Request, keyId string, privateKey string) bool {
-
-	parser := hs.NewParser(
-		hs.WithMinimumRequiredHeaders([]string{
-			hs.Date,
-			hs.Digest,
-			hs.HeaderSignature,
-			hs.Host,
-		}),
-		hs.WithSigningMethods(
-			hs.SigningMethodRsaPssSha512.Alg(),
-			func() hs.SigningMethod { return hs.SigningMethodRsaPssSha512 },
-		),
-		hs.WithSigningMethods(
-			hs.SigningMethodRsaPssSha256.Alg(),
-			func() hs.SigningMethod { return hs.SigningMethodRsaPssSha256 },
-		),
-		// hs.WithValidators(
-		// 	hs.NewDigestUsingSharedValidator(),
-		// 	hs.NewDateValidator(),
-		// ),
-		// hs.WithKeystone(keyStone),
-	)
-	err := parser.AddMetadata(
-		hs.KeyId(keyId),
-		hs.Metadata{
-			Alg:    hs.SigningMethodRsaPssSha512.Name,
-			Key:    []byte(""),
-			Scheme: hs.SchemeSignature,
-		})
-	if err != nil {
-		fmt.Println(err)
-		return false
+// ResendRefundCallback asks pawaPay to resend the callback for a refund in a final state.
+// A REJECTED status is returned in the response rather than as an error.
+func (a *Client) ResendRefundCallback(refundID string) (*ResendRefundCallbackResponse, error) {
+	return a.ResendRefundCallbackContext(context.Background(), refundID)
+}
+
+// ResendRefundCallbackContext is like ResendRefundCallback but uses ctx for the underlying HTTP request
+func (a *Client) ResendRefundCallbackContext(ctx context.Context, refundID string) (*ResendRefundCallbackResponse, error) {
+	if refundID == "" {
+		return nil, fmt.Errorf("refundID is required")
 	}
 
-	gotParam, err := parser.ParseFromRequest(r)
-	if err != nil {
-		fmt.Println(err)
-		return false
+	body := &ResendRefundCallbackResponse{}
+	if err := a.resendCallback(ctx, requestRefundRoute, refundID, body); err != nil {
+		return nil, err
 	}
+	return body, nil
+}
+
+// resendCallback calls the resend-callback endpoint of the given route and decodes the response into body
+func (a *Client) resendCallback(ctx context.Context, route, id string, body any) error {
+	return a.do(ctx, apiRequest{method: http.MethodPost, path: route + "/resend-callback/" + url.PathEscape(id)}, body)
+}
 
-	if err := parser.Verify(r, gotParam); err != nil {
-		fmt.Println(err)
-		return false
+// GetPublicKeys retrieves the public keys pawaPay uses to sign callbacks
+func (a *Client) GetPublicKeys() ([]PublicKeyResponse, error) {
+	return a.GetPublicKeysContext(context.Background())
+}
+
+// GetPublicKeysContext is like GetPublicKeys but uses ctx for the underlying HTTP request
+func (a *Client) GetPublicKeysContext(ctx context.Context) ([]PublicKeyResponse, error) {
+	const publicKeysRoute = "/public-key/http"
+
+	var body []PublicKeyResponse
+	if err := a.do(ctx, apiRequest{method: http.MethodGet, path: publicKeysRoute}, &body); err != nil {
+		return nil, err
 	}
 
-	return true
+	return body, nil
 }
 
+// Component is a covered component of an HTTP message signature, e.g. "@method" or "content-digest"
 type Component struct {
 	Name       string
-	Parameters map[string]string
+	Parameters sfv.Params // Component parameters in order, e.g. {Key: "name", Value: "id"} for "@query-param"
 }
 
 type SignatureParams struct {
 	Components []Component
 	Alg        string
 	Created    int64
+	Expires    int64 // Optional, omitted when zero
 	KeyID      string
 }
 
+// innerList returns the signature parameters as the inner list used in Signature-Input
+func (p SignatureParams) innerList() sfv.InnerList {
+	list := sfv.InnerList{}
+	for _, comp := range p.Components {
+		list.Items = append(list.Items, sfv.Item{Value: comp.Name, Params: comp.Parameters})
+	}
+	if p.Alg != "" {
+		list.Params = append(list.Params, sfv.Param{Key: "alg", Value: p.Alg})
+	}
+	if p.Created != 0 {
+		list.Params = append(list.Params, sfv.Param{Key: "created", Value: p.Created})
+	}
+	if p.Expires != 0 {
+		list.Params = append(list.Params, sfv.Param{Key: "expires", Value: p.Expires})
+	}
+	if p.KeyID != "" {
+		list.Params = append(list.Params, sfv.Param{Key: "keyid", Value: p.KeyID})
+	}
+	return list
+}
+
 // CreateContentDigestHeader generates the SHA-512 content-digest
 func CreateContentDigestHeader(body []byte) string {
 	sum := sha512.Sum512(body)
-	digest := base64.StdEncoding.EncodeToString(sum[:])
-	return fmt.Sprintf("sha-512=:%s:", digest)
+	digest, _ := sfv.Dictionary{{Key: "sha-512", Value: sfv.Item{Value: sum[:]}}}.Serialize()
+	return digest
 }
 
-// CreateSignatureBase returns both the signature base string and Signature-Input header value
+// CreateSignatureBase returns both the signature base string and the Signature-Input
+// value of the signature (without its label)
 func CreateSignatureBase(req *http.Request, body []byte, sigParams SignatureParams) (signatureBase, signatureInput string, err error) {
-	seen := make(map[string]bool)
-	var sb strings.Builder
-	var inputNames []string
-
 	// Calculate Content-Digest header and add it if not present
 	if req.Header.Get("Content-Digest") == "" {
 		digest := CreateContentDigestHeader(body)
 		req.Header.Set("Content-Digest", digest)
 	}
 
-	for _, comp := range sigParams.Components {
-		identifier := serializeComponentIdentifier(comp)
+	return buildSignatureBase(req, nil, sigParams.innerList())
+}
+
+// CreateResponseSignatureBase is like CreateSignatureBase for a response. Components with
+// the req parameter are taken from res.Request. Unlike CreateSignatureBase it doesn't add
+// a Content-Digest header.
+func CreateResponseSignatureBase(res *http.Response, sigParams SignatureParams) (signatureBase, signatureInput string, err error) {
+	return buildSignatureBase(res.Request, res, sigParams.innerList())
+}
+
+// buildSignatureBase builds the signature base for the components and parameters of a
+// Signature-Input inner list and returns it together with the serialized inner list.
+// res is nil when a request is signed.
+func buildSignatureBase(req *http.Request, res *http.Response, input sfv.InnerList) (signatureBase, signatureInput string, err error) {
+	seen := make(map[string]bool)
+	var sb strings.Builder
+
+	for _, item := range input.Items {
+		name, ok := item.Value.(string)
+		if !ok {
+			return "", "", fmt.Errorf("component identifier must be a string, got %T", item.Value)
+		}
+		comp := Component{Name: name, Parameters: item.Params}
+
+		identifier, err := item.Serialize()
+		if err != nil {
+			return "", "", fmt.Errorf("invalid component identifier %s: %v", comp.Name, err)
+		}
 		if seen[identifier] {
 			return "", "", fmt.Errorf("duplicate component identifier: %s", identifier)
 		}
 		seen[identifier] = true
-		inputNames = append(inputNames, fmt.Sprintf(`"%s"`, comp.Name))
 
 		// Build signature base line
 		sb.WriteString(identifier)
 		sb.WriteString(": ")
 
-		value, err := getComponentValue(req, comp)
+		value, err := getComponentValue(req, res, comp)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to get value for %s: %v", comp.Name, err)
 		}
@@ -478,121 +554,226 @@ func CreateSignatureBase(req *http.Request, body []byte, sigParams SignaturePara
 	}
 
 	// Build final signature-params line
-	sigParamsLine := fmt.Sprintf(`"@signature-params": (%s);alg=%s;created=%d;keyid="%s"`,
-		strings.Join(inputNames, " "), sigParams.Alg, sigParams.Created, sigParams.KeyID)
+	signatureInput, err = input.Serialize()
+	if err != nil {
+		return "", "", fmt.Errorf("invalid signature parameters: %v", err)
+	}
 
-	sb.WriteString(sigParamsLine)
+	sb.WriteString(`"@signature-params": `)
+	sb.WriteString(signatureInput)
 
-	return sb.String(), sigParamsLine, nil
+	return sb.String(), signatureInput, nil
 }
 
-func serializeComponentIdentifier(comp Component) string {
-	id := fmt.Sprintf(`"%s"`, comp.Name)
-	for k, v := range comp.Parameters {
-		if v == "" {
-			id += ";" + k
-		} else {
-			id += fmt.Sprintf(`;%s="%s"`, k, v)
+// getComponentValue returns the value of a covered component of req, or of res when res is set.
+// Components of a response with the req parameter are taken from req, the request that
+// triggered the response.
+func getComponentValue(req *http.Request, res *http.Response, comp Component) (string, error) {
+	if _, ok := comp.Parameters.Get("req"); ok {
+		if res == nil || req == nil {
+			return "", fmt.Errorf("req parameter is only allowed when signing a response to a request")
 		}
+		res = nil
 	}
-	return id
-}
 
-func getComponentValue(req *http.Request, comp Component) (string, error) {
 	if strings.HasPrefix(comp.Name, "@") {
-		switch comp.Name {
-		case "@method":
-			return req.Method, nil
-		case "@authority":
-			return req.Host, nil
-		case "@path":
-			return req.URL.Path, nil
-		default:
-			return "", fmt.Errorf("unsupported derived component: %s", comp.Name)
+		return getDerivedComponentValue(req, res, comp)
+	}
+
+	header := http.Header(nil)
+	switch {
+	case res != nil:
+		header = res.Header
+	case req != nil:
+		header = req.Header
+	}
+
+	values := header[http.CanonicalHeaderKey(comp.Name)]
+	if len(values) == 0 {
+		return "", fmt.Errorf("header %s not found", comp.Name)
+	}
+
+	_, sf := comp.Parameters.Get("sf")
+	key, hasKey := comp.Parameters.Get("key")
+	_, bs := comp.Parameters.Get("bs")
+	if bs && (sf || hasKey) {
+		return "", fmt.Errorf("bs parameter can't be combined with sf or key")
+	}
+
+	switch {
+	case bs:
+		// Every field line is wrapped as a byte sequence
+		encoded := make([]string, len(values))
+		for i, v := range values {
+			encoded[i], _ = sfv.Item{Value: []byte(strings.TrimSpace(v))}.Serialize()
+		}
+		return strings.Join(encoded, ", "), nil
+	case hasKey:
+		name, ok := key.(string)
+		if !ok {
+			return "", fmt.Errorf("key parameter must be a string")
+		}
+		dict, err := sfv.ParseDictionary(joinFieldValues(values))
+		if err != nil {
+			return "", fmt.Errorf("header %s is not a dictionary: %v", comp.Name, err)
 		}
-	} else {
-		values := req.Header[http.CanonicalHeaderKey(comp.Name)]
-		if len(values) == 0 {
-			return "", fmt.Errorf("header %s not found", comp.Name)
+		member, ok := dict.Get(name)
+		if !ok {
+			return "", fmt.Errorf("key %s not found in header %s", name, comp.Name)
 		}
-		return strings.Join(values, ", "), nil
+		switch m := member.(type) {
+		case sfv.InnerList:
+			return m.Serialize()
+		case sfv.Item:
+			return m.Serialize()
+		}
+		return "", fmt.Errorf("unsupported member type %T", member)
+	case sf:
+		// Dictionaries and lists cover all structured fields, items parse as single member lists
+		value := joinFieldValues(values)
+		if dict, err := sfv.ParseDictionary(value); err == nil {
+			return dict.Serialize()
+		}
+		list, err := sfv.ParseList(value)
+		if err != nil {
+			return "", fmt.Errorf("header %s is not a structured field: %v", comp.Name, err)
+		}
+		return list.Serialize()
 	}
+
+	return joinFieldValues(values), nil
 }
 
-// PredictProvider predicts the mobile money provider for a given phone number
-func (a *Client) PredictProvider(phoneNumber string) (*PredictProviderResponse, error) {
-	if phoneNumber == "" {
-		return nil, fmt.Errorf("phoneNumber is required")
+// getDerivedComponentValue returns the value of a derived component such as @method or @status
+func getDerivedComponentValue(req *http.Request, res *http.Response, comp Component) (string, error) {
+	if comp.Name == "@status" {
+		if res == nil {
+			return "", fmt.Errorf("@status is only available for responses")
+		}
+		return fmt.Sprintf("%03d", res.StatusCode), nil
+	}
+
+	if res != nil || req == nil {
+		return "", fmt.Errorf("derived component %s requires a request", comp.Name)
+	}
+
+	switch comp.Name {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		return requestScheme(req) + "://" + requestAuthority(req) + req.URL.RequestURI(), nil
+	case "@authority":
+		return requestAuthority(req), nil
+	case "@scheme":
+		return requestScheme(req), nil
+	case "@request-target":
+		return req.URL.RequestURI(), nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	case "@query-param":
+		return getQueryParamValue(req, comp)
+	default:
+		return "", fmt.Errorf("unsupported derived component: %s", comp.Name)
 	}
+}
 
-	httpc := &http.Client{}
-	baseURL := strings.TrimSuffix(a.instanceURL, "/")
-	url := fmt.Sprintf("%s/v2/predict-provider", baseURL)
-
-	requestBody := PredictProviderRequest{
-		PhoneNumber: phoneNumber,
+// getQueryParamValue returns the re-encoded value of the query parameter named by the name parameter
+func getQueryParamValue(req *http.Request, comp Component) (string, error) {
+	param, _ := comp.Parameters.Get("name")
+	name, ok := param.(string)
+	if !ok {
+		return "", fmt.Errorf("@query-param requires a name parameter")
 	}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	var values []string
+	for _, pair := range strings.Split(req.URL.RawQuery, "&") {
+		rawName, rawValue, _ := strings.Cut(pair, "=")
+		decodedName, err := url.QueryUnescape(rawName)
+		if err != nil || encodeQueryComponent(decodedName) != name {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return "", fmt.Errorf("invalid query parameter %s: %v", name, err)
+		}
+		values = append(values, encodeQueryComponent(value))
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	switch len(values) {
+	case 0:
+		return "", fmt.Errorf("query parameter %s not found", name)
+	case 1:
+		return values[0], nil
 	}
+	return "", fmt.Errorf("query parameter %s occurs %d times", name, len(values))
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.authToken)
+// encodeQueryComponent percent-encodes a decoded query name or value, encoding spaces as %20
+func encodeQueryComponent(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
 
-	if a.Debug {
-		// Mask the token for security (show first 8 chars only)
-		maskedToken := a.authToken
-		if len(maskedToken) > 8 {
-			maskedToken = maskedToken[:8] + "..." + maskedToken[len(maskedToken)-4:]
-		}
-		fmt.Println("\n========== DEBUG: REQUEST ==========")
-		fmt.Printf("URL: %s\n", url)
-		fmt.Printf("Method: POST\n")
-		fmt.Printf("Authorization: Bearer %s\n", maskedToken)
-		fmt.Printf("Body: %s\n", string(jsonData))
-		fmt.Println("====================================")
+// requestScheme returns the lowercase scheme of req, which is only set in the URL of outgoing requests
+func requestScheme(req *http.Request) string {
+	if req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
 	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
 
-	resp, err := httpc.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+// requestAuthority returns the lowercase host of req without the default port of its scheme
+func requestAuthority(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
 	}
-	defer resp.Body.Close()
+	host = strings.ToLower(host)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	switch scheme := requestScheme(req); {
+	case scheme == "https" && strings.HasSuffix(host, ":443"):
+		return strings.TrimSuffix(host, ":443")
+	case scheme == "http" && strings.HasSuffix(host, ":80"):
+		return strings.TrimSuffix(host, ":80")
+	}
+	return host
+}
+
+// joinFieldValues combines the field lines of a header as defined by RFC 9421
+func joinFieldValues(values []string) string {
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
 	}
+	return strings.Join(trimmed, ", ")
+}
+
+// PredictProvider predicts the mobile money provider for a given phone number
+func (a *Client) PredictProvider(phoneNumber string) (*PredictProviderResponse, error) {
+	return a.PredictProviderContext(context.Background(), phoneNumber)
+}
 
-	if a.Debug {
-		fmt.Println("\n========== DEBUG: RESPONSE ==========")
-		fmt.Printf("Status: %d %s\n", resp.StatusCode, resp.Status)
-		fmt.Println("Body:")
-		fmt.Println(string(body))
-		fmt.Println("=====================================")
+// PredictProviderContext is like PredictProvider but uses ctx for the underlying HTTP request
+func (a *Client) PredictProviderContext(ctx context.Context, phoneNumber string) (*PredictProviderResponse, error) {
+	if phoneNumber == "" {
+		return nil, fmt.Errorf("phoneNumber is required")
 	}
 
-	// Check if response is an HTTP error (4xx, 5xx)
-	if resp.StatusCode >= 400 {
-		errResp := &ErrorResponse{}
-		if err := json.Unmarshal(body, errResp); err != nil {
-			// If we can't parse the error response, return a generic error
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
-		}
-		return nil, errResp.ToError()
+	requestBody := PredictProviderRequest{
+		PhoneNumber: phoneNumber,
 	}
 
-	var response PredictProviderResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	body := &PredictProviderResponse{}
+	if err := a.do(ctx, apiRequest{method: http.MethodPost, path: predictProviderRoute, body: requestBody}, body); err != nil {
+		return nil, err
 	}
 
-	return &response, nil
+	return body, nil
 }
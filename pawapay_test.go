@@ -1,10 +1,29 @@
 package pawapaygo
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/salticon/pawapay-go-sdk/sfv"
 )
 
 // TestGetActiveConfiguration tests the GetActiveConfiguration method
@@ -699,3 +718,2836 @@ func TestPredictProvider_DifferentCountries(t *testing.T) {
 		})
 	}
 }
+
+// TestInitiatePayout tests the InitiatePayout method with an ACCEPTED response
+func TestInitiatePayout(t *testing.T) {
+	payoutID := "f4401bd2-1568-4140-bf2d-eb77d2b2b639"
+
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verify the request
+		if r.Method != "POST" {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if r.URL.Path != "/v2/payouts" {
+			t.Errorf("Expected path /v2/payouts, got %s", r.URL.Path)
+		}
+
+		// Verify the request body
+		var payload InitiatePayoutRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		if payload.PayoutID != payoutID {
+			t.Errorf("Expected payoutId %s, got %s", payoutID, payload.PayoutID)
+		}
+		if payload.Recipient.AccountDetails.Provider != "MTN_MOMO_ZMB" {
+			t.Errorf("Expected provider MTN_MOMO_ZMB, got %s", payload.Recipient.AccountDetails.Provider)
+		}
+
+		// Send mock response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestPayoutResponse{
+			PayoutID: payoutID,
+			Status:   "ACCEPTED",
+			Created:  "2020-10-19T11:17:01Z",
+		})
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token-12345678",
+	})
+
+	// Call the method
+	response, err := client.InitiatePayout(&InitiatePayoutRequestBody{
+		PayoutID: payoutID,
+		Amount:   "15",
+		Currency: "ZMW",
+		Recipient: Recipient{
+			Type: "MMO",
+			AccountDetails: AccountDetails{
+				PhoneNumber: "260763456789",
+				Provider:    "MTN_MOMO_ZMB",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InitiatePayout failed: %v", err)
+	}
+
+	// Verify response
+	if response.PayoutID != payoutID {
+		t.Errorf("Expected payoutId %s, got %s", payoutID, response.PayoutID)
+	}
+
+	if response.Status != "ACCEPTED" {
+		t.Errorf("Expected status ACCEPTED, got %s", response.Status)
+	}
+}
+
+// TestInitiatePayout_Rejected tests handling of a REJECTED payout
+func TestInitiatePayout_Rejected(t *testing.T) {
+	// Create a test server that rejects the payout
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestPayoutResponse{
+			PayoutID: "f4401bd2-1568-4140-bf2d-eb77d2b2b639",
+			Status:   "REJECTED",
+			FailureReason: FailureReason{
+				FailureCode:    "INSUFFICIENT_BALANCE",
+				FailureMessage: "Not enough funds in the wallet",
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.InitiatePayout(&InitiatePayoutRequestBody{
+		PayoutID: "f4401bd2-1568-4140-bf2d-eb77d2b2b639",
+		Amount:   "15",
+		Currency: "ZMW",
+	})
+	if err == nil {
+		t.Fatal("Expected error for rejected payout, got nil")
+	}
+
+	if !contains(err.Error(), "INSUFFICIENT_BALANCE") {
+		t.Errorf("Expected error to contain failure code, got: %s", err.Error())
+	}
+}
+
+// TestGetPayoutStatus tests the GetPayoutStatus method with FOUND status
+func TestGetPayoutStatus(t *testing.T) {
+	payoutID := "37b250e0-3075-42c8-92a4-6d3d4b3d1b3f"
+
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verify the request
+		if r.Method != "GET" {
+			t.Errorf("Expected GET request, got %s", r.Method)
+		}
+		expectedPath := "/v2/payouts/" + payoutID
+		if r.URL.Path != expectedPath {
+			t.Errorf("Expected path %s, got %s", expectedPath, r.URL.Path)
+		}
+
+		// Send mock response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(CheckPayoutStatusResponse{
+			Status: "FOUND",
+			Data: &PayoutData{
+				PayoutID: payoutID,
+				Status:   "COMPLETED",
+				Amount:   "123.00",
+				Currency: "ZMW",
+				Country:  "ZMB",
+				Recipient: Recipient{
+					Type: "MMO",
+					AccountDetails: AccountDetails{
+						PhoneNumber: "260763456789",
+						Provider:    "MTN_MOMO_ZMB",
+					},
+				},
+				Created: "2020-10-19T08:17:01Z",
+			},
+		})
+	}))
+	defer server.Close()
+
+	// Create client with test server URL
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token-12345678",
+	})
+
+	// Call the method
+	response, err := client.GetPayoutStatus(payoutID)
+	if err != nil {
+		t.Fatalf("GetPayoutStatus failed: %v", err)
+	}
+
+	// Verify response
+	if response.Status != "FOUND" {
+		t.Errorf("Expected status FOUND, got %s", response.Status)
+	}
+
+	if response.Data == nil {
+		t.Fatal("Expected data to be present")
+	}
+
+	if response.Data.PayoutID != payoutID {
+		t.Errorf("Expected payoutId %s, got %s", payoutID, response.Data.PayoutID)
+	}
+
+	if response.Data.Recipient.AccountDetails.PhoneNumber != "260763456789" {
+		t.Errorf("Expected phone number 260763456789, got %s", response.Data.Recipient.AccountDetails.PhoneNumber)
+	}
+}
+
+// TestGetPayoutStatus_EmptyPayoutID tests validation for empty payoutID
+func TestGetPayoutStatus_EmptyPayoutID(t *testing.T) {
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: "http://localhost",
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.GetPayoutStatus("")
+	if err == nil {
+		t.Fatal("Expected error for empty payoutID, got nil")
+	}
+
+	expectedMsg := "payoutID is required"
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message '%s', got: %s", expectedMsg, err.Error())
+	}
+}
+
+// TestInitiateBulkPayout tests that bulk payouts are split into batches and results are reported per item
+func TestInitiateBulkPayout(t *testing.T) {
+	var batchSizes []int
+
+	// Create a test server that rejects every payout with an odd amount
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if r.URL.Path != "/v2/payouts/bulk" {
+			t.Errorf("Expected path /v2/payouts/bulk, got %s", r.URL.Path)
+		}
+
+		var batch []InitiatePayoutRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		batchSizes = append(batchSizes, len(batch))
+
+		results := make([]RequestPayoutResponse, 0, len(batch))
+		for _, payout := range batch {
+			result := RequestPayoutResponse{PayoutID: payout.PayoutID, Status: "ACCEPTED"}
+			if payout.Amount == "1" {
+				result.Status = "REJECTED"
+				result.FailureReason = FailureReason{
+					FailureCode:    "AMOUNT_OUT_OF_BOUNDS",
+					FailureMessage: "Amount is below the minimum",
+				}
+			}
+			results = append(results, result)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	payouts := make([]InitiatePayoutRequestBody, MaxBulkPayoutSize+5)
+	for i := range payouts {
+		payouts[i] = InitiatePayoutRequestBody{
+			PayoutID: fmt.Sprintf("payout-%d", i),
+			Amount:   "100",
+			Currency: "ZMW",
+		}
+	}
+	payouts[3].Amount = "1"
+
+	results, err := client.InitiateBulkPayout(payouts)
+	if err != nil {
+		t.Fatalf("InitiateBulkPayout failed: %v", err)
+	}
+
+	if len(batchSizes) != 2 || batchSizes[0] != MaxBulkPayoutSize || batchSizes[1] != 5 {
+		t.Errorf("Expected batches of %d and 5, got %v", MaxBulkPayoutSize, batchSizes)
+	}
+
+	if len(results) != len(payouts) {
+		t.Fatalf("Expected %d results, got %d", len(payouts), len(results))
+	}
+
+	if results[3].Status != "REJECTED" || results[3].FailureReason.FailureCode != "AMOUNT_OUT_OF_BOUNDS" {
+		t.Errorf("Expected payout 3 to be rejected, got %+v", results[3])
+	}
+
+	if results[len(results)-1].PayoutID != payouts[len(payouts)-1].PayoutID {
+		t.Errorf("Expected results in submission order, got %s last", results[len(results)-1].PayoutID)
+	}
+}
+
+// TestInitiateRefund tests the InitiateRefund method with a partial refund
+func TestInitiateRefund(t *testing.T) {
+	refundID := "ea5b2bf1-0b5a-4a6e-8b4e-1b6d3f4c2a11"
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verify the request
+		if r.Method != "POST" {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if r.URL.Path != "/v2/refunds" {
+			t.Errorf("Expected path /v2/refunds, got %s", r.URL.Path)
+		}
+
+		var payload InitiateRefundRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		if payload.DepositID != depositID {
+			t.Errorf("Expected depositId %s, got %s", depositID, payload.DepositID)
+		}
+		if payload.Amount != "50" {
+			t.Errorf("Expected amount 50, got %s", payload.Amount)
+		}
+
+		// Send mock response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestRefundResponse{
+			RefundID: refundID,
+			Status:   "ACCEPTED",
+			Created:  "2020-10-19T11:17:01Z",
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	response, err := client.InitiateRefund(&InitiateRefundRequestBody{
+		RefundID:  refundID,
+		DepositID: depositID,
+		Amount:    "50",
+		Currency:  "ZMW",
+	})
+	if err != nil {
+		t.Fatalf("InitiateRefund failed: %v", err)
+	}
+
+	if response.RefundID != refundID {
+		t.Errorf("Expected refundId %s, got %s", refundID, response.RefundID)
+	}
+
+	if response.Status != "ACCEPTED" {
+		t.Errorf("Expected status ACCEPTED, got %s", response.Status)
+	}
+}
+
+// TestInitiateRefund_Rejected tests handling of a REJECTED refund
+func TestInitiateRefund_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestRefundResponse{
+			RefundID: "ea5b2bf1-0b5a-4a6e-8b4e-1b6d3f4c2a11",
+			Status:   "REJECTED",
+			FailureReason: FailureReason{
+				FailureCode:    "DEPOSIT_NOT_COMPLETED",
+				FailureMessage: "The deposit has not been completed",
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.InitiateRefund(&InitiateRefundRequestBody{
+		RefundID:  "ea5b2bf1-0b5a-4a6e-8b4e-1b6d3f4c2a11",
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+	})
+	if err == nil {
+		t.Fatal("Expected error for rejected refund, got nil")
+	}
+
+	if !contains(err.Error(), "DEPOSIT_NOT_COMPLETED") {
+		t.Errorf("Expected error to contain failure code, got: %s", err.Error())
+	}
+}
+
+// TestInitiateRefund_RejectedWithoutFailureReason tests that REJECTED without a failure code is still an error
+func TestInitiateRefund_RejectedWithoutFailureReason(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"refundId":"ea5b2bf1-0b5a-4a6e-8b4e-1b6d3f4c2a11","status":"REJECTED"}`))
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	response, err := client.InitiateRefund(&InitiateRefundRequestBody{
+		RefundID:  "ea5b2bf1-0b5a-4a6e-8b4e-1b6d3f4c2a11",
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+	})
+	if !IsRejection(err) {
+		t.Fatalf("Expected rejection error, got %v", err)
+	}
+	if response == nil || response.Status != INITIATION_STATUS_REJECTED {
+		t.Errorf("Expected the rejected response alongside the error, got %+v", response)
+	}
+	if err.Error() != "refund rejected without a failure reason" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+// TestGetRefundStatus tests the GetRefundStatus method with FOUND status
+func TestGetRefundStatus(t *testing.T) {
+	refundID := "ea5b2bf1-0b5a-4a6e-8b4e-1b6d3f4c2a11"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedPath := "/v2/refunds/" + refundID
+		if r.URL.Path != expectedPath {
+			t.Errorf("Expected path %s, got %s", expectedPath, r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(CheckRefundStatusResponse{
+			Status: "FOUND",
+			Data: &RefundData{
+				RefundID:  refundID,
+				DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+				Status:    "COMPLETED",
+				Amount:    "50.00",
+				Currency:  "ZMW",
+				Country:   "ZMB",
+				Created:   "2020-10-19T08:17:01Z",
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	response, err := client.GetRefundStatus(refundID)
+	if err != nil {
+		t.Fatalf("GetRefundStatus failed: %v", err)
+	}
+
+	if response.Data == nil {
+		t.Fatal("Expected data to be present")
+	}
+
+	if response.Data.Status != "COMPLETED" {
+		t.Errorf("Expected status COMPLETED, got %s", response.Data.Status)
+	}
+}
+
+// TestGetRefundStatus_EmptyRefundID tests validation for empty refundID
+func TestGetRefundStatus_EmptyRefundID(t *testing.T) {
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: "http://localhost",
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.GetRefundStatus("")
+	if err == nil {
+		t.Fatal("Expected error for empty refundID, got nil")
+	}
+
+	expectedMsg := "refundID is required"
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message '%s', got: %s", expectedMsg, err.Error())
+	}
+}
+
+// TestCreatePaymentPageSession tests the CreatePaymentPageSession method
+func TestCreatePaymentPageSession(t *testing.T) {
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+	redirectURL := "https://paywith.pawapay.io/?token=abc"
+
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verify the request
+		if r.Method != "POST" {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		if r.URL.Path != "/v2/paymentpage" {
+			t.Errorf("Expected path /v2/paymentpage, got %s", r.URL.Path)
+		}
+
+		var payload PaymentPageRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		if payload.AmountDetails == nil || payload.AmountDetails.Amount != "15" {
+			t.Errorf("Expected amountDetails with amount 15, got %+v", payload.AmountDetails)
+		}
+		if payload.PhoneNumber != "260763456789" {
+			t.Errorf("Expected phoneNumber 260763456789, got %s", payload.PhoneNumber)
+		}
+
+		// Send mock response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(PaymentPageResponse{RedirectURL: redirectURL})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	response, err := client.CreatePaymentPageSession(&PaymentPageRequestBody{
+		DepositID: depositID,
+		ReturnURL: "https://merchant.com/paymentProcessed",
+		AmountDetails: &AmountDetails{
+			Amount:   "15",
+			Currency: "ZMW",
+		},
+		PhoneNumber: "260763456789",
+		Country:     "ZMB",
+		Reason:      "Ticket to festival",
+	})
+	if err != nil {
+		t.Fatalf("CreatePaymentPageSession failed: %v", err)
+	}
+
+	if response.RedirectURL != redirectURL {
+		t.Errorf("Expected redirectUrl %s, got %s", redirectURL, response.RedirectURL)
+	}
+}
+
+// TestCreatePaymentPageSession_MissingReturnURL tests validation for a missing returnUrl
+func TestCreatePaymentPageSession_MissingReturnURL(t *testing.T) {
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: "http://localhost",
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.CreatePaymentPageSession(&PaymentPageRequestBody{
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing returnUrl, got nil")
+	}
+
+	expectedMsg := "returnUrl is required"
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message '%s', got: %s", expectedMsg, err.Error())
+	}
+}
+
+// TestResendCallbacks tests the resend-callback methods for deposits, payouts and refunds
+func TestResendCallbacks(t *testing.T) {
+	id := "8917c345-4791-4285-a416-62f24b6982db"
+
+	// Create a test server that accepts deposits and payouts and rejects refunds
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/v2/deposits/resend-callback/" + id:
+			json.NewEncoder(w).Encode(ResendDepositCallbackResponse{DepositID: id, Status: "ACCEPTED"})
+		case "/v2/payouts/resend-callback/" + id:
+			json.NewEncoder(w).Encode(ResendPayoutCallbackResponse{PayoutID: id, Status: "ACCEPTED"})
+		case "/v2/refunds/resend-callback/" + id:
+			json.NewEncoder(w).Encode(ResendRefundCallbackResponse{
+				RefundID: id,
+				Status:   "REJECTED",
+				FailureReason: &FailureReason{
+					FailureCode:    "INVALID_STATE",
+					FailureMessage: "The refund is not in a final state",
+				},
+			})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	deposit, err := client.ResendDepositCallback(id)
+	if err != nil {
+		t.Fatalf("ResendDepositCallback failed: %v", err)
+	}
+	if deposit.DepositID != id || deposit.Status != "ACCEPTED" {
+		t.Errorf("Unexpected deposit response %+v", deposit)
+	}
+
+	payout, err := client.ResendPayoutCallback(id)
+	if err != nil {
+		t.Fatalf("ResendPayoutCallback failed: %v", err)
+	}
+	if payout.PayoutID != id || payout.Status != "ACCEPTED" {
+		t.Errorf("Unexpected payout response %+v", payout)
+	}
+
+	refund, err := client.ResendRefundCallback(id)
+	if err != nil {
+		t.Fatalf("ResendRefundCallback failed: %v", err)
+	}
+	if refund.Status != "REJECTED" || refund.FailureReason == nil || refund.FailureReason.FailureCode != "INVALID_STATE" {
+		t.Errorf("Expected rejected refund response, got %+v", refund)
+	}
+}
+
+// TestCancelEnqueuedPayout tests cancelling an enqueued payout after checking its status
+func TestCancelEnqueuedPayout(t *testing.T) {
+	payoutID := "37b250e0-3075-42c8-92a4-6d3d4b3d1b3f"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/v2/payouts/" + payoutID:
+			json.NewEncoder(w).Encode(CheckPayoutStatusResponse{
+				Status: "FOUND",
+				Data:   &PayoutData{PayoutID: payoutID, Status: "ENQUEUED"},
+			})
+		case "/v2/payouts/fail-enqueued/" + payoutID:
+			if r.Method != "POST" {
+				t.Errorf("Expected POST request, got %s", r.Method)
+			}
+			json.NewEncoder(w).Encode(CancelEnqueuedPayoutResponse{PayoutID: payoutID, Status: "ACCEPTED"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	status, err := client.GetPayoutStatus(payoutID)
+	if err != nil {
+		t.Fatalf("GetPayoutStatus failed: %v", err)
+	}
+	if !status.Data.CanBeCancelled() {
+		t.Fatal("Expected ENQUEUED payout to be cancellable")
+	}
+
+	response, err := client.CancelEnqueuedPayout(payoutID)
+	if err != nil {
+		t.Fatalf("CancelEnqueuedPayout failed: %v", err)
+	}
+
+	if response.PayoutID != payoutID || response.Status != "ACCEPTED" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+
+	completed := PayoutData{Status: "COMPLETED"}
+	if completed.CanBeCancelled() {
+		t.Error("Expected COMPLETED payout not to be cancellable")
+	}
+}
+
+// TestGetDepositStatusContext_Cancelled tests that a cancelled context aborts the request
+func TestGetDepositStatusContext_Cancelled(t *testing.T) {
+	// Create a test server that responds slower than the context deadline
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetDepositStatusContext(ctx, "8917c345-4791-4285-a416-62f24b6982db")
+	if err == nil {
+		t.Fatal("Expected error for cancelled context, got nil")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// TestNewPawapayClient_CustomTransport tests that all requests go through a configured transport
+func TestNewPawapayClient_CustomTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(WalletBalancesResponse{})
+	}))
+	defer server.Close()
+
+	calls := 0
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	})
+
+	if client.httpClient.Timeout != defaultTimeout {
+		t.Errorf("Expected default timeout %s, got %s", defaultTimeout, client.httpClient.Timeout)
+	}
+
+	if _, err := client.GetWalletBalances(); err != nil {
+		t.Fatalf("GetWalletBalances failed: %v", err)
+	}
+	if _, err := client.GetWalletBalances(); err != nil {
+		t.Fatalf("GetWalletBalances failed: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 calls through the custom transport, got %d", calls)
+	}
+}
+
+// TestNewPawapayClient_CustomHTTPClient tests that a supplied http.Client is used as is
+func TestNewPawapayClient_CustomHTTPClient(t *testing.T) {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+
+	client := NewPawapayClient(&ConfigOptions{
+		ApiToken:   "test-token",
+		HTTPClient: httpClient,
+		Timeout:    time.Minute,
+	})
+
+	if client.httpClient != httpClient {
+		t.Error("Expected the supplied http.Client to be used")
+	}
+}
+
+// TestNewPawapayClient_ReplacedDefaultTransport tests that a replaced http.DefaultTransport is used as is
+func TestNewPawapayClient_ReplacedDefaultTransport(t *testing.T) {
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("not implemented")
+	})
+
+	original := http.DefaultTransport
+	http.DefaultTransport = transport
+	defer func() { http.DefaultTransport = original }()
+
+	client := NewPawapayClient(&ConfigOptions{ApiToken: "test-token"})
+	if _, ok := client.httpClient.Transport.(roundTripperFunc); !ok {
+		t.Errorf("Expected the replaced default transport, got %T", client.httpClient.Transport)
+	}
+}
+
+// TestInitiateDeposit_ErrorResponse tests that HTTP errors are reported before the body is decoded
+func TestInitiateDeposit_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected Content-Type application/json, got %s", r.Header.Get("Content-Type"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Timestamp: "2025-12-28T10:00:00Z",
+			Status:    401,
+			Error:     "Unauthorized",
+			Message:   "Invalid API token",
+			Path:      "/v2/deposits",
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "invalid-token",
+	})
+
+	response, err := client.InitiateDeposit(&InitiateDepositRequestBody{
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+		Amount:    "100",
+		Currency:  "ZMW",
+	})
+	if err == nil {
+		t.Fatalf("Expected error, got response %+v", response)
+	}
+
+	if !contains(err.Error(), "pawapay API error (status 401)") {
+		t.Errorf("Expected error to contain the API error, got: %s", err.Error())
+	}
+}
+
+// TestClient_NonJSONErrorResponse tests that an unparseable error body is reported with its HTTP status
+func TestClient_NonJSONErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream unavailable"))
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.GetWalletBalances()
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	expectedMsg := "HTTP 502: upstream unavailable"
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message '%s', got: %s", expectedMsg, err.Error())
+	}
+}
+
+// TestAPIError_ErrorsAs tests that HTTP errors can be inspected with errors.As and errors.Is
+func TestAPIError_ErrorsAs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"REJECTED","failureReason":{"failureCode":"INVALID_PHONE_NUMBER","failureMessage":"The phone number is not valid"}}`))
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.InitiateDeposit(&InitiateDepositRequestBody{
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+		Amount:    "100",
+		Currency:  "ZMW",
+	})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %T: %v", err, err)
+	}
+
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code 400, got %d", apiErr.StatusCode)
+	}
+	if apiErr.FailureCode != FAILURE_CODE_INVALID_PHONE_NUMBER {
+		t.Errorf("Expected failure code %s, got %s", FAILURE_CODE_INVALID_PHONE_NUMBER, apiErr.FailureCode)
+	}
+	if apiErr.Path != "/v2/deposits" {
+		t.Errorf("Expected path /v2/deposits, got %s", apiErr.Path)
+	}
+	if len(apiErr.RawBody) == 0 {
+		t.Error("Expected raw body to be set")
+	}
+
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected error to match ErrInvalidInput")
+	}
+	if errors.Is(err, ErrAuthentication) {
+		t.Error("Expected error not to match ErrAuthentication")
+	}
+}
+
+// TestAPIError_Rejected tests that REJECTED initiations are returned as *APIError
+func TestAPIError_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestDepositResponse{
+			DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+			Status:    "REJECTED",
+			FailureReason: FailureReason{
+				FailureCode:    FAILURE_CODE_PROVIDER_TEMPORARILY_UNAVAILABLE,
+				FailureMessage: "The provider is temporarily unavailable",
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+	})
+
+	_, err := client.InitiateDeposit(&InitiateDepositRequestBody{
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+		Amount:    "100",
+		Currency:  "ZMW",
+	})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %T: %v", err, err)
+	}
+
+	if apiErr.Operation != "deposit" {
+		t.Errorf("Expected operation deposit, got %s", apiErr.Operation)
+	}
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Error("Expected error to match ErrProviderUnavailable")
+	}
+
+	expectedMsg := "deposit rejected: PROVIDER_TEMPORARILY_UNAVAILABLE - The provider is temporarily unavailable"
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message '%s', got: %s", expectedMsg, err.Error())
+	}
+}
+
+// TestAPIError_StatusCategories tests the sentinel categories derived from HTTP status codes
+func TestAPIError_StatusCategories(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		target     error
+	}{
+		{http.StatusUnauthorized, ErrAuthentication},
+		{http.StatusForbidden, ErrAuthentication},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusServiceUnavailable, ErrServer},
+	}
+
+	for _, tc := range testCases {
+		err := &APIError{StatusCode: tc.statusCode}
+		if !errors.Is(err, tc.target) {
+			t.Errorf("Expected status %d to match %v", tc.statusCode, tc.target)
+		}
+	}
+}
+
+// TestInitiateDeposit_RejectedReturnsResponse tests that a rejected deposit returns both the response and the error
+func TestInitiateDeposit_RejectedReturnsResponse(t *testing.T) {
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+
+	for _, statusCode := range []int{http.StatusOK, http.StatusBadRequest} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(statusCode)
+				json.NewEncoder(w).Encode(RequestDepositResponse{
+					DepositID: depositID,
+					Status:    "REJECTED",
+					Created:   "2025-12-28T10:00:00Z",
+					FailureReason: FailureReason{
+						FailureCode:    FAILURE_CODE_AMOUNT_OUT_OF_BOUNDS,
+						FailureMessage: "The amount is above the maximum",
+					},
+				})
+			}))
+			defer server.Close()
+
+			client := NewPawapayClient(&ConfigOptions{
+				InstanceURL: server.URL,
+				ApiToken:    "test-token",
+			})
+
+			response, err := client.InitiateDeposit(&InitiateDepositRequestBody{
+				DepositID: depositID,
+				Amount:    "100000000",
+				Currency:  "ZMW",
+			})
+			if err == nil {
+				t.Fatal("Expected error for rejected deposit, got nil")
+			}
+			if !IsRejection(err) {
+				t.Errorf("Expected a rejection error, got: %v", err)
+			}
+
+			if response == nil {
+				t.Fatal("Expected the rejected response to be returned")
+			}
+			if response.DepositID != depositID || response.Created != "2025-12-28T10:00:00Z" {
+				t.Errorf("Unexpected response %+v", response)
+			}
+			if response.FailureReason.FailureCode != FAILURE_CODE_AMOUNT_OUT_OF_BOUNDS {
+				t.Errorf("Expected failure code %s, got %s", FAILURE_CODE_AMOUNT_OUT_OF_BOUNDS, response.FailureReason.FailureCode)
+			}
+		})
+	}
+}
+
+// testRetryPolicy returns a retry policy with short delays for tests
+func testRetryPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 10 * time.Millisecond
+	return policy
+}
+
+// TestRetry_ServerError tests that transient server errors are retried
+func TestRetry_ServerError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(WalletBalancesResponse{
+			Balances: []WalletBalance{{Country: "ZMB", Balance: "100.00", Currency: "ZMW"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+		RetryPolicy: testRetryPolicy(),
+	})
+
+	response, err := client.GetWalletBalances()
+	if err != nil {
+		t.Fatalf("GetWalletBalances failed: %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+	if len(response.Balances) != 1 {
+		t.Errorf("Expected 1 balance, got %d", len(response.Balances))
+	}
+}
+
+// TestRetry_MaxAttempts tests that retries stop after MaxAttempts and non-retryable errors are not retried
+func TestRetry_MaxAttempts(t *testing.T) {
+	statusCode := http.StatusBadGateway
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+		RetryPolicy: testRetryPolicy(),
+	})
+
+	if _, err := client.GetWalletBalances(); !errors.Is(err, ErrServer) {
+		t.Errorf("Expected ErrServer, got: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+
+	calls = 0
+	statusCode = http.StatusBadRequest
+	if _, err := client.GetWalletBalances(); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 attempt for a non-retryable status, got %d", calls)
+	}
+}
+
+// TestRetry_LocalErrors tests that local failures aren't retried while connection errors are
+func TestRetry_LocalErrors(t *testing.T) {
+	privateKeyPEM, _ := os.ReadFile("private.pem")
+	signer, err := NewRequestSigner("test-key", privateKeyPEM)
+	if err != nil {
+		t.Fatalf("NewRequestSigner failed: %v", err)
+	}
+	// The request has no such header, so signing fails
+	signer.Components = []Component{{Name: "x-missing-header"}}
+
+	calls := 0
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return nil, fmt.Errorf("connection refused")
+	})
+
+	client := NewPawapayClient(&ConfigOptions{ApiToken: "test-token", RetryPolicy: testRetryPolicy(), Transport: transport, Signer: signer})
+	_, err = client.InitiateDeposit(&InitiateDepositRequestBody{DepositID: "8917c345-4791-4285-a416-62f24b6982db"})
+	if err == nil {
+		t.Fatal("Expected signing error, got nil")
+	}
+	if _, retry := testRetryPolicy().nextDelay(context.Background(), 1, nil, err); retry {
+		t.Errorf("Expected signing error not to be retried: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no request to be sent, got %d", calls)
+	}
+
+	if _, err := client.GetWalletBalances(); err == nil {
+		t.Error("Expected connection error, got nil")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts for a connection error, got %d", calls)
+	}
+}
+
+// TestRetry_RetryAfter tests that the Retry-After header is honored
+func TestRetry_RetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+
+	policy := DefaultRetryPolicy()
+	delay, retry := policy.nextDelay(context.Background(), 1, header, &APIError{StatusCode: http.StatusTooManyRequests})
+	if !retry {
+		t.Fatal("Expected 429 to be retried")
+	}
+	if delay != 3*time.Second {
+		t.Errorf("Expected delay of 3s, got %s", delay)
+	}
+
+	policy.MaxBackoff = time.Second
+	delay, _ = policy.nextDelay(context.Background(), 1, header, &APIError{StatusCode: http.StatusTooManyRequests})
+	if delay != time.Second {
+		t.Errorf("Expected delay capped at 1s, got %s", delay)
+	}
+}
+
+// TestRetry_BackoffBounded tests that the backoff is capped by a default MaxBackoff and can't overflow
+func TestRetry_BackoffBounded(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 100, InitialBackoff: 500 * time.Millisecond}
+
+	for _, attempt := range []int{1, 2, 30, 36, 64, 99} {
+		delay, retry := policy.nextDelay(context.Background(), attempt, nil, &transportError{fmt.Errorf("connection reset")})
+		if !retry {
+			t.Fatalf("Expected attempt %d to be retried", attempt)
+		}
+		if delay <= 0 || delay > defaultMaxBackoff {
+			t.Errorf("Expected attempt %d to wait between 0 and %s, got %s", attempt, defaultMaxBackoff, delay)
+		}
+	}
+
+	policy.MaxBackoff = 3 * time.Second
+	if delay, _ := policy.nextDelay(context.Background(), 3, nil, &transportError{fmt.Errorf("connection reset")}); delay != 2*time.Second {
+		t.Errorf("Expected 2s before the third attempt, got %s", delay)
+	}
+	if delay, _ := policy.nextDelay(context.Background(), 4, nil, &transportError{fmt.Errorf("connection reset")}); delay != 3*time.Second {
+		t.Errorf("Expected 3s before the fourth attempt, got %s", delay)
+	}
+}
+
+// TestRetry_InitiateDepositDuplicateIgnored tests that DUPLICATE_IGNORED on a retry is reported as ACCEPTED
+func TestRetry_InitiateDepositDuplicateIgnored(t *testing.T) {
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// The deposit is stored, but the response is lost
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestDepositResponse{
+			DepositID: depositID,
+			Status:    "DUPLICATE_IGNORED",
+			Created:   "2025-12-28T10:00:00Z",
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+		RetryPolicy: testRetryPolicy(),
+	})
+
+	response, err := client.InitiateDeposit(&InitiateDepositRequestBody{
+		DepositID: depositID,
+		Amount:    "100",
+		Currency:  "ZMW",
+	})
+	if err != nil {
+		t.Fatalf("InitiateDeposit failed: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+	if response.Status != "ACCEPTED" {
+		t.Errorf("Expected status ACCEPTED, got %s", response.Status)
+	}
+	if response.DepositID != depositID {
+		t.Errorf("Expected depositId %s, got %s", depositID, response.DepositID)
+	}
+}
+
+// TestRetry_BulkPayoutDuplicateIgnored tests that DUPLICATE_IGNORED items of a retried batch are reported as ACCEPTED
+func TestRetry_BulkPayoutDuplicateIgnored(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// The batch is stored, but the response is lost
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var batch []InitiatePayoutRequestBody
+		json.NewDecoder(r.Body).Decode(&batch)
+		results := make([]RequestPayoutResponse, len(batch))
+		for i, payout := range batch {
+			results[i] = RequestPayoutResponse{PayoutID: payout.PayoutID, Status: INITIATION_STATUS_DUPLICATE_IGNORED}
+		}
+		results[1].Status = INITIATION_STATUS_REJECTED
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+		RetryPolicy: testRetryPolicy(),
+	})
+
+	results, err := client.InitiateBulkPayout([]InitiatePayoutRequestBody{
+		{PayoutID: "payout-0", Amount: "100", Currency: "ZMW"},
+		{PayoutID: "payout-1", Amount: "100", Currency: "ZMW"},
+		{PayoutID: "payout-2", Amount: "100", Currency: "ZMW"},
+	})
+	if err != nil {
+		t.Fatalf("InitiateBulkPayout failed: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+	expected := []InitiationStatus{INITIATION_STATUS_ACCEPTED, INITIATION_STATUS_REJECTED, INITIATION_STATUS_ACCEPTED}
+	for i, result := range results {
+		if result.Status != expected[i] {
+			t.Errorf("Expected payout %d to be %s, got %s", i, expected[i], result.Status)
+		}
+	}
+}
+
+// verifyTestSignature rebuilds the signature base of a signed request and verifies it with the public key
+func verifyTestSignature(t *testing.T, r *http.Request, body []byte, publicKey crypto.PublicKey, alg string) {
+	t.Helper()
+
+	if r.Header.Get("Content-Digest") != CreateContentDigestHeader(body) {
+		t.Errorf("Expected Content-Digest %s, got %s", CreateContentDigestHeader(body), r.Header.Get("Content-Digest"))
+	}
+
+	input, ok := strings.CutPrefix(r.Header.Get("Signature-Input"), "sig-pp=")
+	if !ok {
+		t.Fatalf("Expected Signature-Input labelled sig-pp, got %q", r.Header.Get("Signature-Input"))
+	}
+	for _, param := range []string{`;alg="` + alg + `"`, ";created=", ";expires=", `;keyid="test-key"`} {
+		if !contains(input, param) {
+			t.Errorf("Expected Signature-Input to contain %s, got %s", param, input)
+		}
+	}
+
+	encoded, ok := strings.CutPrefix(r.Header.Get("Signature"), "sig-pp=:")
+	if !ok {
+		t.Fatalf("Expected Signature labelled sig-pp, got %q", r.Header.Get("Signature"))
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(encoded, ":"))
+	if err != nil {
+		t.Fatalf("Failed to decode signature: %v", err)
+	}
+
+	var base strings.Builder
+	for _, comp := range DefaultSignedComponents {
+		value, err := getComponentValue(r, nil, comp)
+		if err != nil {
+			t.Fatalf("Missing signed component: %v", err)
+		}
+		base.WriteString(`"` + comp.Name + `": ` + value + "\n")
+	}
+	base.WriteString(`"@signature-params": ` + input)
+
+	if err := verifyMessage(alg, publicKey, []byte(base.String()), signature); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+}
+
+// TestInitiateDeposit_SignedRequest tests that the Client signs requests with the configured RSA key
+func TestInitiateDeposit_SignedRequest(t *testing.T) {
+	privateKeyPEM, err := os.ReadFile("private.pem")
+	if err != nil {
+		t.Fatalf("Failed to read private key: %v", err)
+	}
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+	block, _ := pem.Decode(publicKeyPEM)
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+
+	signer, err := NewRequestSigner("test-key", privateKeyPEM)
+	if err != nil {
+		t.Fatalf("NewRequestSigner failed: %v", err)
+	}
+	if signer.Alg() != SIGNATURE_ALG_RSA_PSS_SHA512 {
+		t.Errorf("Expected alg %s, got %s", SIGNATURE_ALG_RSA_PSS_SHA512, signer.Alg())
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyTestSignature(t, r, body, publicKey, SIGNATURE_ALG_RSA_PSS_SHA512)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(RequestDepositResponse{Status: "ACCEPTED"})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{
+		InstanceURL: server.URL,
+		ApiToken:    "test-token",
+		Signer:      signer,
+	})
+
+	if _, err := client.InitiateDeposit(&InitiateDepositRequestBody{
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+		Amount:    "100",
+		Currency:  "ZMW",
+	}); err != nil {
+		t.Fatalf("InitiateDeposit failed: %v", err)
+	}
+}
+
+// TestRequestSigner_ECDSA tests signing with EC keys in SEC 1 and PKCS#8 format
+func TestRequestSigner_ECDSA(t *testing.T) {
+	tests := []struct {
+		curve elliptic.Curve
+		alg   string
+	}{
+		{elliptic.P256(), SIGNATURE_ALG_ECDSA_P256_SHA256},
+		{elliptic.P384(), SIGNATURE_ALG_ECDSA_P384_SHA384},
+	}
+
+	for _, tt := range tests {
+		key, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		sec1, _ := x509.MarshalECPrivateKey(key)
+		pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+
+		for _, block := range []*pem.Block{{Type: "EC PRIVATE KEY", Bytes: sec1}, {Type: "PRIVATE KEY", Bytes: pkcs8}} {
+			signer, err := NewRequestSigner("test-key", pem.EncodeToMemory(block))
+			if err != nil {
+				t.Fatalf("NewRequestSigner failed for %s: %v", block.Type, err)
+			}
+			if signer.Alg() != tt.alg {
+				t.Errorf("Expected alg %s, got %s", tt.alg, signer.Alg())
+			}
+
+			body := []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db"}`)
+			req := httptest.NewRequest(http.MethodPost, "https://api.sandbox.pawapay.io/v2/deposits", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if err := signer.Sign(req, body); err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+
+			verifyTestSignature(t, req, body, &key.PublicKey, tt.alg)
+		}
+	}
+}
+
+// TestNewRequestSigner_InvalidKey tests that invalid keys are rejected
+func TestNewRequestSigner_InvalidKey(t *testing.T) {
+	if _, err := NewRequestSigner("test-key", []byte("not a key")); err == nil {
+		t.Error("Expected error for invalid PEM, got nil")
+	}
+
+	privateKeyPEM, _ := os.ReadFile("private.pem")
+	if _, err := NewRequestSigner("", privateKeyPEM); err == nil {
+		t.Error("Expected error for empty keyID, got nil")
+	}
+}
+
+// signedTestCallback returns a callback request signed with private.pem and the matching public keys
+func signedTestCallback(t *testing.T, body []byte) (*http.Request, PublicKeys) {
+	t.Helper()
+
+	privateKeyPEM, err := os.ReadFile("private.pem")
+	if err != nil {
+		t.Fatalf("Failed to read private key: %v", err)
+	}
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+	publicKey, err := ParsePublicKeyPEM(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM failed: %v", err)
+	}
+
+	signer, err := NewRequestSigner("test-key", privateKeyPEM)
+	if err != nil {
+		t.Fatalf("NewRequestSigner failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "https://merchant.example.com/callbacks/deposits", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if err := signer.Sign(req, body); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	return req, PublicKeys{"test-key": publicKey}
+}
+
+// TestVerifySignature tests verification of a signed callback
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"COMPLETED"}`)
+	req, keys := signedTestCallback(t, body)
+
+	if err := VerifySignature(req, body, keys); err != nil {
+		t.Fatalf("VerifySignature failed: %v", err)
+	}
+}
+
+// TestVerifySignature_Invalid tests that tampered, expired and unverifiable callbacks are rejected
+func TestVerifySignature_Invalid(t *testing.T) {
+	body := []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"COMPLETED"}`)
+
+	tests := []struct {
+		name    string
+		modify  func(r *http.Request, keys PublicKeys) []byte
+		wantErr string
+	}{
+		{
+			name: "tampered body",
+			modify: func(r *http.Request, keys PublicKeys) []byte {
+				return []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"FAILED"}`)
+			},
+			wantErr: "content digest does not match",
+		},
+		{
+			name: "tampered body and digest",
+			modify: func(r *http.Request, keys PublicKeys) []byte {
+				tampered := []byte(`{"status":"FAILED"}`)
+				r.Header.Set("Content-Digest", CreateContentDigestHeader(tampered))
+				return tampered
+			},
+			wantErr: "verification error",
+		},
+		{
+			name: "unknown key",
+			modify: func(r *http.Request, keys PublicKeys) []byte {
+				delete(keys, "test-key")
+				return body
+			},
+			wantErr: `unknown key id "test-key"`,
+		},
+		{
+			name: "missing signature",
+			modify: func(r *http.Request, keys PublicKeys) []byte {
+				r.Header.Del("Signature")
+				return body
+			},
+			wantErr: "missing Signature header",
+		},
+		{
+			name: "expired",
+			modify: func(r *http.Request, keys PublicKeys) []byte {
+				expired := fmt.Sprintf(`sig-pp=("content-digest");created=%d;expires=%d;keyid="test-key"`,
+					time.Now().Add(-time.Hour).Unix(), time.Now().Add(-time.Minute).Unix())
+				r.Header.Set("Signature-Input", expired)
+				return body
+			},
+			wantErr: "signature expired",
+		},
+		{
+			name: "digest not covered",
+			modify: func(r *http.Request, keys PublicKeys) []byte {
+				r.Header.Set("Signature-Input", `sig-pp=("@method");keyid="test-key"`)
+				return body
+			},
+			wantErr: "content-digest is not covered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, keys := signedTestCallback(t, body)
+			received := tt.modify(req, keys)
+
+			err := VerifySignature(req, received, keys)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature, got %v", err)
+			}
+			if !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestGetPublicKeys tests the GetPublicKeys method
+func TestGetPublicKeys(t *testing.T) {
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+	block, _ := pem.Decode(publicKeyPEM)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/public-key/http" {
+			t.Errorf("Expected path /v2/public-key/http, got %s", r.URL.Path)
+		}
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected GET request, got %s", r.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode([]PublicKeyResponse{
+			{ID: "key-1", Key: base64.StdEncoding.EncodeToString(block.Bytes)},
+			{ID: "key-2", Key: string(publicKeyPEM)},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"})
+
+	keys, err := client.GetPublicKeys()
+	if err != nil {
+		t.Fatalf("GetPublicKeys failed: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keys))
+	}
+
+	for _, k := range keys {
+		key, err := k.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey failed for %s: %v", k.ID, err)
+		}
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			t.Errorf("Expected *rsa.PublicKey for %s, got %T", k.ID, key)
+		}
+	}
+}
+
+// TestKeyStore_RefetchOnUnknownKeyID tests that a rotated key is fetched when a callback uses it
+func TestKeyStore_RefetchOnUnknownKeyID(t *testing.T) {
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		keys := []PublicKeyResponse{{ID: "old-key", Key: string(publicKeyPEM)}}
+		if calls > 1 {
+			keys = append(keys, PublicKeyResponse{ID: "test-key", Key: string(publicKeyPEM)})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(keys)
+	}))
+	defer server.Close()
+
+	store := NewKeyStore(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	store.RefetchInterval = time.Nanosecond
+
+	if err := store.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	body := []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"COMPLETED"}`)
+	req, _ := signedTestCallback(t, body)
+	if err := VerifySignature(req, body, store); err != nil {
+		t.Fatalf("VerifySignature failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 fetches, got %d", calls)
+	}
+
+	// Known keys are served from the cache
+	if _, err := store.PublicKey(context.Background(), "test-key"); err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected cached key, got %d fetches", calls)
+	}
+
+	if _, err := store.PublicKey(context.Background(), "missing-key"); err == nil {
+		t.Error("Expected error for unknown key id, got nil")
+	}
+}
+
+// TestKeyStore_TTL tests that keys are refetched once the TTL has passed
+func TestKeyStore_TTL(t *testing.T) {
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode([]PublicKeyResponse{{ID: "test-key", Key: string(publicKeyPEM)}})
+	}))
+	defer server.Close()
+
+	store := NewKeyStore(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	store.TTL = time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		if _, err := store.PublicKey(context.Background(), "test-key"); err != nil {
+			t.Fatalf("PublicKey failed: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 fetches, got %d", calls)
+	}
+}
+
+// TestKeyStore_InvalidKey tests that keys that can't be parsed are skipped instead of failing the refresh
+func TestKeyStore_InvalidKey(t *testing.T) {
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+
+	keys := []PublicKeyResponse{{ID: "new-key", Key: "bm90IGEga2V5"}, {ID: "test-key", Key: string(publicKeyPEM)}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(keys)
+	}))
+	defer server.Close()
+
+	store := NewKeyStore(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	var invalid []string
+	store.OnInvalidKey = func(keyID string, err error) { invalid = append(invalid, keyID) }
+
+	if err := store.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, err := store.PublicKey(context.Background(), "test-key"); err != nil {
+		t.Errorf("Expected test-key to be usable, got %v", err)
+	}
+	if len(invalid) != 1 || invalid[0] != "new-key" {
+		t.Errorf("Expected new-key to be reported as invalid, got %v", invalid)
+	}
+
+	// Without any usable key, the cached keys are kept
+	keys = keys[:1]
+	if err := store.Refresh(context.Background()); err == nil {
+		t.Error("Expected error when no key parses, got nil")
+	}
+	if _, err := store.PublicKey(context.Background(), "test-key"); err != nil {
+		t.Errorf("Expected cached test-key to stay usable, got %v", err)
+	}
+}
+
+// TestKeyStore_FailedRefresh tests that failed fetches aren't repeated for every callback
+func TestKeyStore_FailedRefresh(t *testing.T) {
+	publicKeyPEM, err := os.ReadFile("public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]PublicKeyResponse{{ID: "test-key", Key: string(publicKeyPEM)}})
+	}))
+	defer server.Close()
+
+	store := NewKeyStore(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	store.TTL = time.Millisecond
+	store.RefetchInterval = time.Hour
+
+	if _, err := store.PublicKey(context.Background(), "test-key"); err != nil {
+		t.Fatalf("PublicKey failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if _, err := store.PublicKey(context.Background(), "test-key"); err != nil {
+			t.Fatalf("Expected the expired key to stay in use, got %v", err)
+		}
+		if _, err := store.PublicKey(context.Background(), "rotated-key"); err == nil {
+			t.Error("Expected error for unknown key id, got nil")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected 1 failed refetch, got %d fetches", calls)
+	}
+}
+
+// TestGetComponentValue tests derived components and component parameters using the examples of RFC 9421
+func TestGetComponentValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://www.Example.com/path?param=value&foo=bar&baz=batman&qux=&var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace", nil)
+	req.Host = "www.Example.com:443"
+	req.Header.Set("Example-Dict", " a=1,    b=2;x=1;y=2,   c=(a   b   c), d")
+	req.Header.Add("Example-Header", "value, with, lots")
+	req.Header.Add("Example-Header", "of, commas")
+
+	tests := []struct {
+		comp Component
+		want string
+	}{
+		{Component{Name: "@method"}, "POST"},
+		{Component{Name: "@target-uri"}, "https://www.example.com/path?param=value&foo=bar&baz=batman&qux=&var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace"},
+		{Component{Name: "@authority"}, "www.example.com"},
+		{Component{Name: "@scheme"}, "https"},
+		{Component{Name: "@request-target"}, "/path?param=value&foo=bar&baz=batman&qux=&var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace"},
+		{Component{Name: "@path"}, "/path"},
+		{Component{Name: "@query"}, "?param=value&foo=bar&baz=batman&qux=&var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace"},
+		{Component{Name: "@query-param", Parameters: sfv.Params{{Key: "name", Value: "baz"}}}, "batman"},
+		{Component{Name: "@query-param", Parameters: sfv.Params{{Key: "name", Value: "qux"}}}, ""},
+		{Component{Name: "@query-param", Parameters: sfv.Params{{Key: "name", Value: "var"}}}, "this%20is%20a%20big%0Avalue"},
+		{Component{Name: "@query-param", Parameters: sfv.Params{{Key: "name", Value: "bar"}}}, "with%20plus%20whitespace"},
+		{Component{Name: "example-dict"}, "a=1,    b=2;x=1;y=2,   c=(a   b   c), d"},
+		{Component{Name: "example-dict", Parameters: sfv.Params{{Key: "sf", Value: true}}}, "a=1, b=2;x=1;y=2, c=(a b c), d"},
+		{Component{Name: "example-dict", Parameters: sfv.Params{{Key: "key", Value: "a"}}}, "1"},
+		{Component{Name: "example-dict", Parameters: sfv.Params{{Key: "key", Value: "c"}}}, "(a b c)"},
+		{Component{Name: "example-dict", Parameters: sfv.Params{{Key: "key", Value: "d"}}}, "?1"},
+		{Component{Name: "example-header"}, "value, with, lots, of, commas"},
+		{Component{Name: "example-header", Parameters: sfv.Params{{Key: "bs", Value: true}}}, ":dmFsdWUsIHdpdGgsIGxvdHM=:, :b2YsIGNvbW1hcw==:"},
+	}
+
+	for _, tt := range tests {
+		got, err := getComponentValue(req, nil, tt.comp)
+		if err != nil {
+			t.Errorf("%s %v: unexpected error: %v", tt.comp.Name, tt.comp.Parameters, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %v: expected %q, got %q", tt.comp.Name, tt.comp.Parameters, tt.want, got)
+		}
+	}
+
+	invalid := []Component{
+		{Name: "@status"},
+		{Name: "@unknown"},
+		{Name: "@query-param"},
+		{Name: "@query-param", Parameters: sfv.Params{{Key: "name", Value: "missing"}}},
+		{Name: "@method", Parameters: sfv.Params{{Key: "req", Value: true}}},
+		{Name: "example-dict", Parameters: sfv.Params{{Key: "sf", Value: true}, {Key: "bs", Value: true}}},
+		{Name: "example-dict", Parameters: sfv.Params{{Key: "key", Value: "missing"}}},
+		{Name: "missing-header"},
+	}
+	for _, comp := range invalid {
+		if _, err := getComponentValue(req, nil, comp); err == nil {
+			t.Errorf("%s %v: expected error, got nil", comp.Name, comp.Parameters)
+		}
+	}
+}
+
+// TestCreateResponseSignatureBase tests @status and components of the request with the req parameter
+func TestCreateResponseSignatureBase(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/foo?param=Value&Pet=dog", nil)
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Request:    req,
+	}
+
+	base, input, err := CreateResponseSignatureBase(res, SignatureParams{
+		Components: []Component{
+			{Name: "@status"},
+			{Name: "content-type"},
+			{Name: "@method", Parameters: sfv.Params{{Key: "req", Value: true}}},
+			{Name: "@authority", Parameters: sfv.Params{{Key: "req", Value: true}}},
+		},
+		Created: 1618884473,
+		KeyID:   "test-key",
+	})
+	if err != nil {
+		t.Fatalf("CreateResponseSignatureBase failed: %v", err)
+	}
+
+	expectedInput := `("@status" "content-type" "@method";req "@authority";req);created=1618884473;keyid="test-key"`
+	if input != expectedInput {
+		t.Errorf("Expected signature input %s, got %s", expectedInput, input)
+	}
+
+	expectedBase := `"@status": 200
+"content-type": application/json
+"@method";req: POST
+"@authority";req: example.com
+"@signature-params": ` + expectedInput
+	if base != expectedBase {
+		t.Errorf("Expected signature base\n%s\ngot\n%s", expectedBase, base)
+	}
+
+	if _, _, err := CreateResponseSignatureBase(res, SignatureParams{Components: []Component{{Name: "@method"}}}); err == nil {
+		t.Error("Expected error for @method without req on a response, got nil")
+	}
+}
+
+// TestVerifyContentDigest tests sha-256 and sha-512 Content-Digest headers
+func TestVerifyContentDigest(t *testing.T) {
+	body := []byte(`{"hello": "world"}`)
+
+	// Digests from RFC 9530 for the body above
+	sha256Digest := "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"
+	sha512Digest := "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"
+
+	valid := []string{
+		sha256Digest,
+		sha512Digest,
+		sha256Digest + ", " + sha512Digest,
+		"md5=:Y2hlY2s=:, " + sha256Digest,
+		CreateContentDigestHeader(body),
+	}
+	for _, header := range valid {
+		if err := VerifyContentDigest(header, body); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", header, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"md5=:Y2hlY2s=:",
+		"sha-256=:AAAA:",
+		sha256Digest + ", sha-512=:AAAA:",
+		"sha-256=abc",
+		"not a dictionary",
+	}
+	for _, header := range invalid {
+		if err := VerifyContentDigest(header, body); err == nil {
+			t.Errorf("Expected error for %q, got nil", header)
+		}
+	}
+}
+
+// TestContentDigestMiddleware tests that the raw body is buffered and mismatching digests are rejected
+func TestContentDigestMiddleware(t *testing.T) {
+	body := []byte(`{"depositId": "8917c345-4791-4285-a416-62f24b6982db"}`)
+
+	handler := ContentDigestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := RawBody(r)
+		if !ok || !bytes.Equal(raw, body) {
+			t.Errorf("Expected raw body %s, got %s", body, raw)
+		}
+		read, _ := io.ReadAll(r.Body)
+		if !bytes.Equal(read, body) {
+			t.Errorf("Expected r.Body to still contain %s, got %s", body, read)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		body   []byte
+		digest string
+		status int
+	}{
+		{"valid digest", body, CreateContentDigestHeader(body), http.StatusOK},
+		{"no digest", body, "", http.StatusOK},
+		{"mismatch", body, CreateContentDigestHeader([]byte("{}")), http.StatusBadRequest},
+		{"too large", bytes.Repeat([]byte("a"), MaxCallbackBodySize+1), "", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/callbacks/deposits", bytes.NewReader(tt.body))
+			if tt.digest != "" {
+				req.Header.Set("Content-Digest", tt.digest)
+			}
+			rec := httptest.NewRecorder()
+
+			if tt.status == http.StatusOK {
+				handler.ServeHTTP(rec, req)
+			} else {
+				ContentDigestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					t.Error("Handler must not be called")
+				})).ServeHTTP(rec, req)
+			}
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+// TestWebhookHandler tests that callbacks are dispatched by type and status
+func TestWebhookHandler(t *testing.T) {
+	var called []string
+	handler := &WebhookHandler{
+		OnDepositCompleted: func(ctx context.Context, callback *DepositCallback) error {
+			called = append(called, "deposit completed "+callback.DepositID)
+			return nil
+		},
+		OnDepositFailed: func(ctx context.Context, callback *DepositCallback) error {
+			called = append(called, "deposit failed "+callback.FailureReason.FailureCode)
+			return nil
+		},
+		OnPayoutFailed: func(ctx context.Context, callback *PayoutData) error {
+			called = append(called, "payout failed "+callback.PayoutID)
+			return nil
+		},
+		OnRefundCompleted: func(ctx context.Context, callback *RefundData) error {
+			if callback.DepositID == "" {
+				t.Error("Expected refund callback to carry the depositId")
+			}
+			return fmt.Errorf("database unavailable")
+		},
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		called string
+	}{
+		{"deposit completed", `{"depositId":"d-1","status":"COMPLETED"}`, http.StatusOK, "deposit completed d-1"},
+		{"deposit failed", `{"depositId":"d-2","status":"FAILED","failureReason":{"failureCode":"PAYER_NOT_FOUND"}}`, http.StatusOK, "deposit failed PAYER_NOT_FOUND"},
+		{"payout failed", `{"payoutId":"p-1","status":"FAILED"}`, http.StatusOK, "payout failed p-1"},
+		{"payout completed without handler", `{"payoutId":"p-2","status":"COMPLETED"}`, http.StatusOK, ""},
+		{"refund handler error", `{"refundId":"r-1","depositId":"d-1","status":"COMPLETED"}`, http.StatusInternalServerError, ""},
+		{"invalid json", `{"depositId":`, http.StatusBadRequest, ""},
+		{"unknown callback", `{"status":"COMPLETED"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			req := httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if tt.called != "" && (len(called) != 1 || called[0] != tt.called) {
+				t.Errorf("Expected %q to be called, got %v", tt.called, called)
+			}
+		})
+	}
+}
+
+// TestWebhookHandler_Signature tests that signed callbacks are verified when Keys is set
+func TestWebhookHandler_Signature(t *testing.T) {
+	body := []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"COMPLETED"}`)
+	req, keys := signedTestCallback(t, body)
+
+	var callbackErr error
+	completed := 0
+	handler := &WebhookHandler{
+		Keys: keys,
+		OnDepositCompleted: func(ctx context.Context, callback *DepositCallback) error {
+			completed++
+			return nil
+		},
+		OnError: func(r *http.Request, err error) {
+			callbackErr = err
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ContentDigestMiddleware(handler).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %v", rec.Code, callbackErr)
+	}
+	if completed != 1 {
+		t.Errorf("Expected OnDepositCompleted to be called once, got %d", completed)
+	}
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/callbacks", bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, unsigned)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for unsigned callback, got %d", rec.Code)
+	}
+	if !errors.Is(callbackErr, ErrInvalidSignature) {
+		t.Errorf("Expected OnError with ErrInvalidSignature, got %v", callbackErr)
+	}
+	if completed != 1 {
+		t.Errorf("Expected unsigned callback not to be dispatched")
+	}
+}
+
+// TestParseDepositCallback tests decoding v2 and legacy v1 deposit callbacks into the v2 model
+func TestParseDepositCallback(t *testing.T) {
+	v2 := `{
+		"depositId": "8917c345-4791-4285-a416-62f24b6982db",
+		"status": "COMPLETED",
+		"amount": "123.00",
+		"currency": "ZMW",
+		"country": "ZMB",
+		"payer": {"type": "MMO", "accountDetails": {"phoneNumber": "260763456789", "provider": "MTN_MOMO_ZMB"}},
+		"customerMessage": "Note of 4 to 22 chars",
+		"created": "2020-02-21T17:32:28Z",
+		"providerTransactionId": "ABC123",
+		"metadata": [{"orderId": "ORD-123456789"}, {"customerId": "customer@email.com", "isPII": true}]
+	}`
+
+	v1 := `{
+		"depositId": "8917c345-4791-4285-a416-62f24b6982db",
+		"status": "FAILED",
+		"requestedAmount": "123.00",
+		"depositedAmount": "",
+		"currency": "ZMW",
+		"country": "ZMB",
+		"correspondent": "MTN_MOMO_ZMB",
+		"payer": {"type": "MSISDN", "address": {"value": "260763456789"}},
+		"customerTimestamp": "2020-02-21T17:32:29.000Z",
+		"statementDescription": "Note of 4 to 22 chars",
+		"created": "2020-02-21T17:32:28Z",
+		"failureReason": {"failureCode": "INSUFFICIENT_BALANCE", "failureMessage": "Not enough funds"},
+		"metadata": {"orderId": "ORD-123456789", "customerId": "customer@email.com"}
+	}`
+
+	for name, body := range map[string]string{"v2": v2, "v1": v1} {
+		callback, err := ParseDepositCallback([]byte(body))
+		if err != nil {
+			t.Fatalf("%s: ParseDepositCallback failed: %v", name, err)
+		}
+
+		if callback.DepositID != "8917c345-4791-4285-a416-62f24b6982db" {
+			t.Errorf("%s: unexpected depositId %s", name, callback.DepositID)
+		}
+		if callback.Amount != "123.00" {
+			t.Errorf("%s: expected amount 123.00, got %s", name, callback.Amount)
+		}
+		if callback.Payer.AccountDetails.Provider != "MTN_MOMO_ZMB" {
+			t.Errorf("%s: expected provider MTN_MOMO_ZMB, got %s", name, callback.Payer.AccountDetails.Provider)
+		}
+		if callback.Payer.AccountDetails.PhoneNumber != "260763456789" {
+			t.Errorf("%s: expected phone number 260763456789, got %s", name, callback.Payer.AccountDetails.PhoneNumber)
+		}
+		if callback.CustomerMessage != "Note of 4 to 22 chars" {
+			t.Errorf("%s: unexpected customerMessage %s", name, callback.CustomerMessage)
+		}
+		if len(callback.Metadata) != 2 || callback.Metadata[0]["orderId"] != "ORD-123456789" || callback.Metadata[1]["customerId"] != "customer@email.com" {
+			t.Errorf("%s: unexpected metadata %v", name, callback.Metadata)
+		}
+	}
+
+	callback, _ := ParseDepositCallback([]byte(v2))
+	if callback.ProviderTransactionID != "ABC123" {
+		t.Errorf("Expected providerTransactionId ABC123, got %s", callback.ProviderTransactionID)
+	}
+
+	legacy, _ := ParseDepositCallback([]byte(v1))
+	if legacy.FailureReason == nil || legacy.FailureReason.FailureCode != FAILURE_CODE_INSUFFICIENT_BALANCE {
+		t.Errorf("Expected failure code INSUFFICIENT_BALANCE, got %+v", legacy.FailureReason)
+	}
+}
+
+// TestMemoryIdempotencyStore tests claiming, completing, releasing and expiring keys
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryIdempotencyStore(0)
+
+	if claimed, _ := store.Claim(ctx, "deposit:d-1:COMPLETED"); !claimed {
+		t.Error("Expected first claim to succeed")
+	}
+	if claimed, _ := store.Claim(ctx, "deposit:d-1:COMPLETED"); claimed {
+		t.Error("Expected second claim to fail")
+	}
+
+	store.Release(ctx, "deposit:d-1:COMPLETED")
+	if claimed, _ := store.Claim(ctx, "deposit:d-1:COMPLETED"); !claimed {
+		t.Error("Expected claim after release to succeed")
+	}
+
+	store.Complete(ctx, "deposit:d-1:COMPLETED")
+	if claimed, _ := store.Claim(ctx, "deposit:d-1:COMPLETED"); claimed {
+		t.Error("Expected claim of a completed key to fail")
+	}
+
+	// A claim that is neither completed nor released, e.g. because the handler hung, expires
+	store.Claim(ctx, "deposit:d-2:COMPLETED")
+	store.claimed["deposit:d-2:COMPLETED"] = time.Now().Add(-idempotencyClaimTimeout - time.Second)
+	if claimed, _ := store.Claim(ctx, "deposit:d-2:COMPLETED"); !claimed {
+		t.Error("Expected claim after the claim timeout to succeed")
+	}
+
+	expiring := NewMemoryIdempotencyStore(time.Millisecond)
+	expiring.Claim(ctx, "deposit:d-1:COMPLETED")
+	expiring.Complete(ctx, "deposit:d-1:COMPLETED")
+	time.Sleep(5 * time.Millisecond)
+	if claimed, _ := expiring.Claim(ctx, "deposit:d-1:COMPLETED"); !claimed {
+		t.Error("Expected claim after TTL to succeed")
+	}
+}
+
+// TestFileIdempotencyStore tests that completed keys survive reopening the store while claims don't
+func TestFileIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/callbacks.log"
+
+	store, err := OpenFileIdempotencyStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileIdempotencyStore failed: %v", err)
+	}
+	if claimed, err := store.Claim(ctx, "deposit:d-1:COMPLETED"); err != nil || !claimed {
+		t.Fatalf("Expected first claim to succeed, got %v, %v", claimed, err)
+	}
+	if err := store.Complete(ctx, "deposit:d-1:COMPLETED"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	// Claimed but never completed, as when the process crashes while handling the callback
+	store.Claim(ctx, "refund:r-1:COMPLETED")
+	store.Claim(ctx, "payout:p-1:FAILED")
+	if err := store.Release(ctx, "payout:p-1:FAILED"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := store.Claim(ctx, "bad\nkey"); err == nil {
+		t.Error("Expected error for key with newline, got nil")
+	}
+	store.Close()
+
+	reopened, err := OpenFileIdempotencyStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileIdempotencyStore failed: %v", err)
+	}
+	defer reopened.Close()
+
+	if claimed, _ := reopened.Claim(ctx, "deposit:d-1:COMPLETED"); claimed {
+		t.Error("Expected key completed before reopening to be remembered")
+	}
+	if claimed, _ := reopened.Claim(ctx, "refund:r-1:COMPLETED"); !claimed {
+		t.Error("Expected key that was never completed to be claimable after reopening")
+	}
+	if claimed, _ := reopened.Claim(ctx, "payout:p-1:FAILED"); !claimed {
+		t.Error("Expected released key to be claimable after reopening")
+	}
+}
+
+// TestWebhookHandler_Idempotency tests that duplicate callbacks are acknowledged but processed once
+func TestWebhookHandler_Idempotency(t *testing.T) {
+	processed := 0
+	fail := true
+	handler := &WebhookHandler{
+		Idempotency: NewMemoryIdempotencyStore(0),
+		OnDepositCompleted: func(ctx context.Context, callback *DepositCallback) error {
+			processed++
+			return nil
+		},
+		OnPayoutCompleted: func(ctx context.Context, callback *PayoutCallback) error {
+			if fail {
+				fail = false
+				return fmt.Errorf("database unavailable")
+			}
+			processed++
+			return nil
+		},
+	}
+
+	deliver := func(body string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if status := deliver(`{"depositId":"d-1","status":"COMPLETED"}`); status != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", status)
+		}
+	}
+	if processed != 1 {
+		t.Errorf("Expected duplicate deposit callback to be processed once, got %d", processed)
+	}
+
+	// A failed callback is released so pawaPay's redelivery is processed
+	if status := deliver(`{"payoutId":"p-1","status":"COMPLETED"}`); status != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", status)
+	}
+	if status := deliver(`{"payoutId":"p-1","status":"COMPLETED"}`); status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+	if processed != 2 {
+		t.Errorf("Expected redelivered payout callback to be processed, got %d", processed)
+	}
+}
+
+// TestWebhookHandler_IdempotencyPanic tests that a callback whose function panics is processed on redelivery
+func TestWebhookHandler_IdempotencyPanic(t *testing.T) {
+	processed := 0
+	handler := &WebhookHandler{
+		Idempotency: NewMemoryIdempotencyStore(0),
+		OnDepositCompleted: func(ctx context.Context, callback *DepositCallback) error {
+			processed++
+			if processed == 1 {
+				panic("nil map")
+			}
+			return nil
+		},
+	}
+
+	deliver := func() (status int, panicked bool) {
+		defer func() {
+			panicked = recover() != nil
+		}()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(`{"depositId":"d-1","status":"COMPLETED"}`)))
+		return rec.Code, false
+	}
+
+	if _, panicked := deliver(); !panicked {
+		t.Error("Expected the panic to propagate")
+	}
+	if status, _ := deliver(); status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+	if processed != 2 {
+		t.Errorf("Expected redelivered callback to be processed, got %d calls", processed)
+	}
+}
+
+// TestWebhookHandler_MaxSignatureAgeWithoutKeys tests that MaxSignatureAge without Keys rejects callbacks
+func TestWebhookHandler_MaxSignatureAgeWithoutKeys(t *testing.T) {
+	var callbackErr error
+	processed := 0
+	handler := &WebhookHandler{
+		MaxSignatureAge: time.Second,
+		OnDepositCompleted: func(ctx context.Context, callback *DepositCallback) error {
+			processed++
+			return nil
+		},
+		OnError: func(r *http.Request, err error) {
+			callbackErr = err
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(`{"depositId":"d-1","status":"COMPLETED"}`)))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", rec.Code)
+	}
+	if processed != 0 {
+		t.Errorf("Expected no processed callbacks, got %d", processed)
+	}
+	if callbackErr == nil {
+		t.Error("Expected error to be reported to OnError")
+	}
+}
+
+// TestWebhookHandler_MaxSignatureAge tests that stale signed callbacks are acknowledged but not processed
+func TestWebhookHandler_MaxSignatureAge(t *testing.T) {
+	body := []byte(`{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"COMPLETED"}`)
+
+	tests := []struct {
+		maxAge    time.Duration
+		processed int
+		stale     bool
+	}{
+		{time.Minute, 1, false},
+		{time.Nanosecond, 0, true},
+	}
+
+	for _, tt := range tests {
+		req, keys := signedTestCallback(t, body)
+		time.Sleep(2 * time.Millisecond)
+
+		var callbackErr error
+		processed := 0
+		handler := &WebhookHandler{
+			Keys:            keys,
+			MaxSignatureAge: tt.maxAge,
+			OnDepositCompleted: func(ctx context.Context, callback *DepositCallback) error {
+				processed++
+				return nil
+			},
+			OnError: func(r *http.Request, err error) {
+				callbackErr = err
+			},
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("MaxSignatureAge %s: expected status 200, got %d", tt.maxAge, rec.Code)
+		}
+		if processed != tt.processed {
+			t.Errorf("MaxSignatureAge %s: expected %d processed callbacks, got %d", tt.maxAge, tt.processed, processed)
+		}
+		if errors.Is(callbackErr, ErrStaleCallback) != tt.stale {
+			t.Errorf("MaxSignatureAge %s: unexpected error %v", tt.maxAge, callbackErr)
+		}
+	}
+}
+
+// testDepositTracker returns a tracker with short poll intervals
+func testDepositTracker(client PawapayAPIClient) *DepositTracker {
+	tracker := NewDepositTracker(client)
+	tracker.PollInterval = time.Millisecond
+	tracker.MaxPollInterval = 5 * time.Millisecond
+	tracker.NotFoundPeriod = 50 * time.Millisecond
+	tracker.Timeout = time.Second
+	return tracker
+}
+
+// TestDepositTracker_Polling tests that polling finds the final status after the deposit propagated
+func TestDepositTracker_Polling(t *testing.T) {
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		n := polls
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case n == 1:
+			json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "NOT_FOUND"})
+		case n == 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case n < 5:
+			json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "FOUND", Data: &DepositData{DepositID: depositID, Status: "SUBMITTED"}})
+		default:
+			json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "FOUND", Data: &DepositData{DepositID: depositID, Status: "COMPLETED"}})
+		}
+	}))
+	defer server.Close()
+
+	tracker := testDepositTracker(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	subscription := tracker.Subscribe(context.Background(), depositID)
+
+	deposit, err := tracker.WaitForFinal(context.Background(), depositID)
+	if err != nil {
+		t.Fatalf("WaitForFinal failed: %v", err)
+	}
+	if deposit.Status != "COMPLETED" {
+		t.Errorf("Expected status COMPLETED, got %s", deposit.Status)
+	}
+
+	result, ok := <-subscription
+	if !ok || result.Err != nil || result.Deposit.Status != "COMPLETED" {
+		t.Errorf("Expected subscription to receive COMPLETED, got %+v", result)
+	}
+	if _, ok := <-subscription; ok {
+		t.Error("Expected subscription channel to be closed")
+	}
+}
+
+// TestDepositTracker_Callback tests that a callback completes tracking before polling does
+func TestDepositTracker_Callback(t *testing.T) {
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "FOUND", Data: &DepositData{DepositID: depositID, Status: "ACCEPTED"}})
+	}))
+	defer server.Close()
+
+	tracker := testDepositTracker(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	tracker.Track(depositID)
+
+	handler := &WebhookHandler{
+		OnDepositCompleted: tracker.HandleCallback,
+		OnDepositFailed:    tracker.HandleCallback,
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		body := `{"depositId":"` + depositID + `","status":"FAILED","failureReason":{"failureCode":"PAYER_NOT_FOUND"}}`
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(body)))
+	}()
+
+	deposit, err := tracker.WaitForFinal(context.Background(), depositID)
+	if err != nil {
+		t.Fatalf("WaitForFinal failed: %v", err)
+	}
+	if deposit.Status != "FAILED" || deposit.FailureReason.FailureCode != "PAYER_NOT_FOUND" {
+		t.Errorf("Expected FAILED with PAYER_NOT_FOUND, got %+v", deposit)
+	}
+}
+
+// TestDepositTracker_CallbackBeforeWait tests that the final status from a callback is kept for later waiters
+func TestDepositTracker_CallbackBeforeWait(t *testing.T) {
+	depositID := "8917c345-4791-4285-a416-62f24b6982db"
+
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "NOT_FOUND"})
+	}))
+	defer server.Close()
+
+	tracker := testDepositTracker(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+	tracker.PollInterval = time.Hour
+	tracker.ResultRetention = 50 * time.Millisecond
+
+	tracker.Track(depositID)
+	tracker.HandleCallback(context.Background(), &DepositCallback{DepositID: depositID, Status: DEPOSIT_STATUS_COMPLETED})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	deposit, err := tracker.WaitForFinal(ctx, depositID)
+	if err != nil {
+		t.Fatalf("WaitForFinal failed: %v", err)
+	}
+	if deposit.Status != DEPOSIT_STATUS_COMPLETED {
+		t.Errorf("Expected status COMPLETED, got %s", deposit.Status)
+	}
+	mu.Lock()
+	if polls != 0 {
+		t.Errorf("Expected no polls, got %d", polls)
+	}
+	mu.Unlock()
+
+	// After ResultRetention the deposit is tracked from scratch
+	time.Sleep(100 * time.Millisecond)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := tracker.WaitForFinal(ctx, depositID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded once the result expired, got %v", err)
+	}
+}
+
+// TestDepositTracker_NotFound tests that tracking fails once NOT_FOUND persists past the propagation window
+func TestDepositTracker_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "NOT_FOUND"})
+	}))
+	defer server.Close()
+
+	tracker := testDepositTracker(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+
+	_, err := tracker.WaitForFinal(context.Background(), "8917c345-4791-4285-a416-62f24b6982db")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tracker.WaitForFinal(ctx, "8917c345-4791-4285-a416-62f24b6982db"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestDepositTracker_StopPolling tests that polling stops once nobody waits for the deposit
+func TestDepositTracker_StopPolling(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CheckDepositStatusResponse{Status: "FOUND", Data: &DepositData{Status: "ACCEPTED"}})
+	}))
+	defer server.Close()
+
+	countPolls := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return polls
+	}
+	// assertStopped checks that no further polls are made
+	assertStopped := func(name string) {
+		time.Sleep(20 * time.Millisecond)
+		before := countPolls()
+		time.Sleep(20 * time.Millisecond)
+		if after := countPolls(); after != before {
+			t.Errorf("Expected polling to stop after %s, got %d more polls", name, after-before)
+		}
+	}
+
+	tracker := testDepositTracker(NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := tracker.WaitForFinal(ctx, "d-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	assertStopped("the waiter gave up")
+
+	subscriptionCtx, cancelSubscription := context.WithCancel(context.Background())
+	subscription := tracker.Subscribe(subscriptionCtx, "d-2")
+	cancelSubscription()
+	if result, ok := <-subscription; ok {
+		t.Errorf("Expected subscription to be closed without a result, got %+v", result)
+	}
+	assertStopped("the subscription was cancelled")
+
+	tracker.Track("d-3")
+	waiting := tracker.Subscribe(context.Background(), "d-3")
+	time.Sleep(20 * time.Millisecond)
+	tracker.Untrack("d-3")
+	if result := <-waiting; !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Expected context.Canceled after Untrack, got %v", result.Err)
+	}
+	assertStopped("Untrack")
+}
+
+// TestDepositStatus tests the final and success helpers and the transition validator
+func TestDepositStatus(t *testing.T) {
+	if !DEPOSIT_STATUS_COMPLETED.IsFinal() || !DEPOSIT_STATUS_COMPLETED.IsSuccess() {
+		t.Error("Expected COMPLETED to be final and successful")
+	}
+	if !DEPOSIT_STATUS_FAILED.IsFinal() || DEPOSIT_STATUS_FAILED.IsSuccess() {
+		t.Error("Expected FAILED to be final and not successful")
+	}
+	if DEPOSIT_STATUS_SUBMITTED.IsFinal() {
+		t.Error("Expected SUBMITTED not to be final")
+	}
+
+	tests := []struct {
+		from, to DepositStatus
+		valid    bool
+	}{
+		{DEPOSIT_STATUS_ACCEPTED, DEPOSIT_STATUS_SUBMITTED, true},
+		{DEPOSIT_STATUS_SUBMITTED, DEPOSIT_STATUS_COMPLETED, true},
+		{DEPOSIT_STATUS_ACCEPTED, DEPOSIT_STATUS_FAILED, true},
+		{DEPOSIT_STATUS_COMPLETED, DEPOSIT_STATUS_COMPLETED, true},
+		{DEPOSIT_STATUS_SUBMITTED, DEPOSIT_STATUS_ACCEPTED, false},
+		{DEPOSIT_STATUS_COMPLETED, DEPOSIT_STATUS_FAILED, false},
+		{DEPOSIT_STATUS_ACCEPTED, DEPOSIT_STATUS_REJECTED, false},
+		{DEPOSIT_STATUS_ACCEPTED, "AWAITING_OTP", true},
+		{DEPOSIT_STATUS_FAILED, "AWAITING_OTP", false},
+	}
+	for _, tt := range tests {
+		err := tt.from.ValidateTransition(tt.to)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected transition %s -> %s valid=%v, got %v", tt.from, tt.to, tt.valid, err)
+		}
+	}
+}
+
+// TestPayoutAndRefundStatus tests the typed statuses of payouts and refunds
+func TestPayoutAndRefundStatus(t *testing.T) {
+	var payout CheckPayoutStatusResponse
+	body := `{"status":"FOUND","data":{"payoutId":"37b250e0-3075-42e8-92a4-16d3fc7a0b49","status":"ENQUEUED"}}`
+	if err := json.Unmarshal([]byte(body), &payout); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !payout.Status.IsFound() || payout.Data.Status != PAYOUT_STATUS_ENQUEUED {
+		t.Errorf("Expected FOUND and ENQUEUED, got %s and %s", payout.Status, payout.Data.Status)
+	}
+	if !payout.Data.IsEnqueued() || payout.Data.Status.IsFinal() {
+		t.Error("Expected ENQUEUED to be enqueued and not final")
+	}
+	if !PAYOUT_STATUS_COMPLETED.IsSuccess() || PAYOUT_STATUS_FAILED.IsSuccess() || !PAYOUT_STATUS_FAILED.IsFinal() {
+		t.Error("Expected COMPLETED to be successful and FAILED to be final and not successful")
+	}
+
+	var refund RequestRefundResponse
+	if err := json.Unmarshal([]byte(`{"refundId":"r-1","status":"ACCEPTED"}`), &refund); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !refund.Status.IsSuccess() {
+		t.Errorf("Expected status ACCEPTED, got %s", refund.Status)
+	}
+	if !REFUND_STATUS_COMPLETED.IsFinal() || REFUND_STATUS_PROCESSING.IsFinal() || RefundStatus("AWAITING").IsKnown() {
+		t.Error("Expected COMPLETED to be final, PROCESSING not final and AWAITING unknown")
+	}
+}
+
+// TestStatus_UnknownValues tests that unknown statuses are decoded as they are
+func TestStatus_UnknownValues(t *testing.T) {
+	var response CheckDepositStatusResponse
+	body := `{"status":"FOUND","data":{"depositId":"8917c345-4791-4285-a416-62f24b6982db","status":"AWAITING_OTP"}}`
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if !response.Status.IsFound() {
+		t.Errorf("Expected status FOUND, got %s", response.Status)
+	}
+	if response.Data.Status != "AWAITING_OTP" {
+		t.Errorf("Expected status AWAITING_OTP, got %s", response.Data.Status)
+	}
+	if response.Data.Status.IsKnown() || response.Data.Status.IsFinal() {
+		t.Error("Expected AWAITING_OTP to be unknown and not final")
+	}
+
+	var initiation RequestDepositResponse
+	if err := json.Unmarshal([]byte(`{"status":"DUPLICATE_IGNORED"}`), &initiation); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !initiation.Status.IsKnown() || initiation.Status.IsSuccess() {
+		t.Errorf("Expected DUPLICATE_IGNORED to be known and not successful, got %s", initiation.Status)
+	}
+}
+
+// TestParseAmount tests parsing and formatting of amounts
+func TestParseAmount(t *testing.T) {
+	for _, s := range []string{"0", "0.5", "0.05", "5", "12.50", "1000", "100000.99"} {
+		amount, err := ParseAmount(s)
+		if err != nil {
+			t.Errorf("Expected %q to be valid, got %v", s, err)
+			continue
+		}
+		if amount.String() != s {
+			t.Errorf("Expected %q to format as itself, got %q", s, amount.String())
+		}
+	}
+
+	for _, s := range []string{"", "5.", ".5", "00.5", "01", "-1", "+1", "1e3", "1.234", "1,5", " 1", "1234567890123456"} {
+		if _, err := ParseAmount(s); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Expected %q to be rejected with ErrInvalidInput, got %v", s, err)
+		}
+	}
+}
+
+// TestAmount_Arithmetic tests comparing and adding amounts without float rounding
+func TestAmount_Arithmetic(t *testing.T) {
+	sum := MustParseAmount("0.1").Add(MustParseAmount("0.2"))
+	if sum.String() != "0.3" {
+		t.Errorf("Expected 0.1 + 0.2 = 0.3, got %s", sum)
+	}
+	if sum := MustParseAmount("5").Add(MustParseAmount("0.25")); sum.String() != "5.25" {
+		t.Errorf("Expected 5 + 0.25 = 5.25, got %s", sum)
+	}
+
+	if MustParseAmount("5").Cmp(MustParseAmount("5.00")) != 0 {
+		t.Error("Expected 5 and 5.00 to be equal")
+	}
+	if MustParseAmount("9.99").Cmp(MustParseAmount("10")) != -1 {
+		t.Error("Expected 9.99 to be less than 10")
+	}
+
+	var payload struct {
+		Amount Amount `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"12.50"}`), &payload); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	b, _ := json.Marshal(payload)
+	if string(b) != `{"amount":"12.50"}` {
+		t.Errorf("Expected amount to round-trip, got %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":".5"}`), &payload); err == nil {
+		t.Error("Expected unmarshalling .5 to fail")
+	}
+}
+
+// TestValidateAmount tests amounts against the limits of the active configuration
+func TestValidateAmount(t *testing.T) {
+	cfg := &ActiveConfigurationResponse{
+		Countries: []CountryConfig{{
+			Country: "ZMB",
+			Providers: []ProviderConfig{{
+				Provider: "MTN_MOMO_ZMB",
+				Currencies: []CurrencyConfig{{
+					Currency: "ZMW",
+					OperationTypes: map[string]OperationType{
+						OPERATION_TYPE_DEPOSIT: {MinTransactionLimit: "1", MaxTransactionLimit: "100000", DecimalsInAmount: DECIMALS_IN_AMOUNT_NONE},
+						OPERATION_TYPE_PAYOUT:  {MinTransactionLimit: "0.5", MaxTransactionLimit: "5000", DecimalsInAmount: DECIMALS_IN_AMOUNT_TWO_PLACES},
+					},
+				}},
+			}},
+		}},
+	}
+
+	tests := []struct {
+		amount, provider, currency, operation string
+		valid                                 bool
+	}{
+		{"500", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_DEPOSIT, true},
+		{"100000", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_DEPOSIT, true},
+		{"500.50", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_DEPOSIT, false},
+		{"0", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_DEPOSIT, false},
+		{"100001", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_DEPOSIT, false},
+		{"0.5", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_PAYOUT, true},
+		{"0.49", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_PAYOUT, false},
+		{"500", "MTN_MOMO_ZMB", "ZMW", OPERATION_TYPE_REFUND, false},
+		{"500", "MTN_MOMO_ZMB", "USD", OPERATION_TYPE_DEPOSIT, false},
+		{"500", "AIRTEL_OAPI_ZMB", "ZMW", OPERATION_TYPE_DEPOSIT, false},
+	}
+	for _, tt := range tests {
+		err := cfg.ValidateAmount(MustParseAmount(tt.amount), tt.provider, tt.currency, tt.operation)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected %s %s for %s/%s valid=%v, got %v", tt.operation, tt.amount, tt.provider, tt.currency, tt.valid, err)
+		}
+	}
+}
+
+// testValidationConfig returns an active configuration with one provider for request validation tests
+func testValidationConfig() *ActiveConfigurationResponse {
+	return &ActiveConfigurationResponse{
+		Countries: []CountryConfig{{
+			Country: "ZMB",
+			Prefix:  "260",
+			Providers: []ProviderConfig{{
+				Provider: "MTN_MOMO_ZMB",
+				Currencies: []CurrencyConfig{{
+					Currency: "ZMW",
+					OperationTypes: map[string]OperationType{
+						OPERATION_TYPE_DEPOSIT: {MinTransactionLimit: "1", MaxTransactionLimit: "100000", DecimalsInAmount: DECIMALS_IN_AMOUNT_NONE, Status: OPERATION_STATUS_OPERATIONAL},
+						OPERATION_TYPE_PAYOUT:  {MinTransactionLimit: "1", MaxTransactionLimit: "100000", DecimalsInAmount: DECIMALS_IN_AMOUNT_NONE, Status: OPERATION_STATUS_CLOSED},
+					},
+				}},
+			}},
+		}},
+	}
+}
+
+// testValidationFields returns the fields of a ValidationErrors
+func testValidationFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ValidationErrors to match ErrInvalidInput")
+	}
+
+	fields := make([]string, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		fields[i] = fieldErr.Field
+	}
+	return fields
+}
+
+// TestInitiateDepositRequestBody_Validate tests pre-flight validation of deposits
+func TestInitiateDepositRequestBody_Validate(t *testing.T) {
+	deposit := InitiateDepositRequestBody{
+		DepositID:       "8917c345-4791-4285-a416-62f24b6982db",
+		Payer:           Payer{Type: "MMO", AccountDetails: AccountDetails{PhoneNumber: "260763456789", Provider: "MTN_MOMO_ZMB"}},
+		Amount:          "500",
+		Currency:        "ZMW",
+		CustomerMessage: "Order 12345",
+	}
+	if err := deposit.Validate(testValidationConfig()); err != nil {
+		t.Errorf("Expected valid deposit, got %v", err)
+	}
+
+	invalid := deposit
+	invalid.DepositID = "order-12345"
+	invalid.Currency = "USD"
+	invalid.CustomerMessage = "Thanks for shopping at our store!"
+	invalid.Payer.AccountDetails.PhoneNumber = "+255763456789"
+	fields := testValidationFields(t, invalid.Validate(testValidationConfig()))
+
+	expected := []string{"depositId", "customerMessage", "payer.accountDetails.phoneNumber", "payer.accountDetails.phoneNumber", "currency"}
+	if strings.Join(fields, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected errors for %v, got %v", expected, fields)
+	}
+
+	invalid = deposit
+	invalid.Amount = "500.50"
+	fields = testValidationFields(t, invalid.Validate(testValidationConfig()))
+	if len(fields) != 1 || fields[0] != "amount" {
+		t.Errorf("Expected an amount error, got %v", fields)
+	}
+
+	// Without a configuration, only the format of the fields is checked
+	invalid.Amount = ".5"
+	fields = testValidationFields(t, invalid.Validate(nil))
+	if len(fields) != 1 || fields[0] != "amount" {
+		t.Errorf("Expected an amount error, got %v", fields)
+	}
+}
+
+// TestInitiatePayoutRequestBody_Validate tests that payouts to CLOSED providers are rejected
+func TestInitiatePayoutRequestBody_Validate(t *testing.T) {
+	payout := InitiatePayoutRequestBody{
+		PayoutID:  "8917c345-4791-4285-a416-62f24b6982db",
+		Recipient: Recipient{Type: "MMO", AccountDetails: AccountDetails{PhoneNumber: "260763456789", Provider: "MTN_MOMO_ZMB"}},
+		Amount:    "500",
+		Currency:  "ZMW",
+	}
+
+	fields := testValidationFields(t, payout.Validate(testValidationConfig()))
+	if len(fields) != 1 || fields[0] != "recipient.accountDetails.provider" {
+		t.Errorf("Expected a provider error, got %v", fields)
+	}
+
+	refund := InitiateRefundRequestBody{RefundID: "not-a-uuid", DepositID: payout.PayoutID, Amount: "100"}
+	fields = testValidationFields(t, refund.Validate(testValidationConfig()))
+	if strings.Join(fields, ",") != "refundId,currency" {
+		t.Errorf("Expected errors for refundId and currency, got %v", fields)
+	}
+}
+
+// TestClient_ValidateRequests tests that invalid requests fail without being sent
+func TestClient_ValidateRequests(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v2/active-conf":
+			json.NewEncoder(w).Encode(testValidationConfig())
+		default:
+			json.NewEncoder(w).Encode(RequestDepositResponse{DepositID: "8917c345-4791-4285-a416-62f24b6982db", Status: INITIATION_STATUS_ACCEPTED})
+		}
+	}))
+	defer server.Close()
+
+	client := NewPawapayClient(&ConfigOptions{InstanceURL: server.URL, ApiToken: "test-token", ValidateRequests: true})
+	deposit := &InitiateDepositRequestBody{
+		DepositID: "8917c345-4791-4285-a416-62f24b6982db",
+		Payer:     Payer{Type: "MMO", AccountDetails: AccountDetails{PhoneNumber: "260763456789", Provider: "MTN_MOMO_ZMB"}},
+		Amount:    "500000",
+		Currency:  "ZMW",
+	}
+
+	if _, err := client.InitiateDeposit(deposit); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+
+	deposit.Amount = "500"
+	if _, err := client.InitiateDeposit(deposit); err != nil {
+		t.Errorf("InitiateDeposit failed: %v", err)
+	}
+
+	if requests["/v2/active-conf"] != 1 {
+		t.Errorf("Expected the active configuration to be fetched once, got %d", requests["/v2/active-conf"])
+	}
+	if requests["/v2/deposits"] != 1 {
+		t.Errorf("Expected 1 deposit request, got %d", requests["/v2/deposits"])
+	}
+}
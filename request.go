@@ -0,0 +1,209 @@
+package pawapaygo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// apiRequest describes a single call to the pawaPay API
+type apiRequest struct {
+	method string
+	path   string // Path relative to the /v2 API root, e.g. "/deposits"
+	body   any    // Marshalled to JSON when not nil
+
+	// operation names the initiation request, e.g. "deposit". When set, a REJECTED
+	// status in a successful response is reported as an *APIError.
+	operation string
+}
+
+// apiResponse holds the raw result of a successful call to the pawaPay API
+type apiResponse struct {
+	statusCode int
+	path       string
+	body       []byte
+	attempts   int // Number of attempts it took to get the response
+}
+
+// do sends the request through the shared pipeline and decodes a successful response into out.
+// out may be nil when the response body is not needed. For rejected initiation requests the
+// response is still decoded into out and an *APIError is returned.
+func (a *Client) do(ctx context.Context, r apiRequest, out any) error {
+	res, err := a.send(ctx, r)
+	if err != nil {
+		// pawaPay may also answer a rejected initiation with a 4xx status
+		var apiErr *APIError
+		if r.operation != "" && errors.As(err, &apiErr) {
+			rejected := &apiResponse{statusCode: apiErr.StatusCode, path: apiErr.Path, body: apiErr.RawBody}
+			if rejErr := checkRejection(r.operation, rejected); rejErr != nil {
+				if out != nil {
+					json.Unmarshal(apiErr.RawBody, out)
+				}
+				return rejErr
+			}
+		}
+		return err
+	}
+
+	if out != nil && len(res.body) > 0 {
+		// Parse the response body
+		if err := json.Unmarshal(res.body, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		if initiation, ok := out.(initiationResponse); ok && res.attempts > 1 {
+			initiation.acceptDuplicate()
+		}
+	}
+
+	if r.operation != "" {
+		return checkRejection(r.operation, res)
+	}
+
+	return nil
+}
+
+// send executes the request and returns the raw response. Every call to the pawaPay API
+// goes through here so URL building, headers, debug logging, retries and error handling
+// are the same for all endpoints.
+func (a *Client) send(ctx context.Context, r apiRequest) (*apiResponse, error) {
+	var payload []byte
+	if r.body != nil {
+		var err error
+		payload, err = json.Marshal(r.body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, header, err := a.sendOnce(ctx, r, payload)
+		if res != nil {
+			res.attempts = attempt
+		}
+
+		delay, retry := a.retryPolicy.nextDelay(ctx, attempt, header, err)
+		if !retry {
+			return res, err
+		}
+
+		if a.Debug {
+			fmt.Printf("\n========== DEBUG: RETRY %d in %s (%v) ==========\n", attempt+1, delay, err)
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, fmt.Errorf("failed to execute request: %w", err)
+		}
+	}
+}
+
+// sendOnce makes a single attempt of the request. The response headers are returned
+// together with HTTP errors so the retry policy can honor Retry-After.
+func (a *Client) sendOnce(ctx context.Context, r apiRequest, payload []byte) (*apiResponse, http.Header, error) {
+	// Build the URL, ensuring no double slashes
+	baseURL := strings.TrimSuffix(a.instanceURL, "/")
+	url := baseURL + "/v2" + r.path
+
+	// Create an http request
+	req, err := http.NewRequestWithContext(ctx, r.method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Add required http headers
+	req.Header.Set("Authorization", "Bearer "+a.authToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// Sign every attempt separately so created and expires stay fresh across retries
+	if a.signer != nil && payload != nil {
+		if err := a.signer.Sign(req, payload); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	a.debugRequest(req, payload)
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, &transportError{fmt.Errorf("failed to execute request: %w", err)}
+	}
+	// Close response body stream in the end
+	defer res.Body.Close()
+
+	// Read response body
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, &transportError{fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	a.debugResponse(res, resBody)
+
+	// Check if response is an HTTP error (4xx, 5xx)
+	if res.StatusCode >= 400 {
+		return nil, res.Header, decodeErrorResponse(res.StatusCode, req.URL.Path, resBody)
+	}
+
+	return &apiResponse{statusCode: res.StatusCode, path: req.URL.Path, body: resBody}, res.Header, nil
+}
+
+// transportError reports a failure to reach pawaPay or to read its response, such as a
+// connection error or timeout. Unlike local failures, e.g. signing errors, it is retried.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// debugRequest prints the outgoing request when debug mode is enabled
+func (a *Client) debugRequest(req *http.Request, body []byte) {
+	if !a.Debug {
+		return
+	}
+
+	fmt.Println("\n========== DEBUG: REQUEST ==========")
+	fmt.Printf("Method: %s\n", req.Method)
+	fmt.Printf("URL: %s\n", req.URL)
+	fmt.Printf("Authorization: Bearer %s\n", maskToken(a.authToken))
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		fmt.Printf("Content-Type: %s\n", ct)
+	}
+	if body != nil {
+		fmt.Println("Body:")
+		fmt.Println(string(body))
+	}
+	fmt.Println("====================================")
+}
+
+// debugResponse prints the received response when debug mode is enabled
+func (a *Client) debugResponse(res *http.Response, body []byte) {
+	if !a.Debug {
+		return
+	}
+
+	fmt.Println("\n========== DEBUG: RESPONSE ==========")
+	fmt.Printf("Status: %s\n", res.Status)
+	fmt.Println("Body:")
+	fmt.Println(string(body))
+	fmt.Println("=====================================")
+}
+
+// maskToken hides the API token for logging (show first 8 and last 4 chars only)
+func maskToken(token string) string {
+	if len(token) > 8 {
+		return token[:8] + "..." + token[len(token)-4:]
+	}
+	return token
+}
@@ -0,0 +1,155 @@
+package pawapaygo
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures automatic retries of failed requests. All pawaPay endpoints are
+// safe to retry: lookups have no side effects and deposits, payouts and refunds are
+// idempotent on their ID. A retried deposit, payout, refund or bulk payout batch that pawaPay
+// answers with DUPLICATE_IGNORED is reported as ACCEPTED, per payout for bulk payouts, since
+// it means an earlier attempt already went through.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles with every further retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including delays requested with Retry-After.
+	// Defaults to 10 seconds.
+	MaxBackoff time.Duration
+	// Jitter randomizes every delay by up to this fraction, e.g. 0.2 for ±20%.
+	Jitter float64
+	// RetryableStatusCodes lists the HTTP status codes that are retried.
+	// Connection errors and timeouts are always retried, local failures such as signing errors never.
+	RetryableStatusCodes []int
+}
+
+const defaultMaxBackoff = 10 * time.Second
+
+// DefaultRetryPolicy returns a policy with 3 attempts and exponential backoff starting at 500ms
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     defaultMaxBackoff,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// nextDelay reports whether the failed attempt should be retried and how long to wait before doing so
+func (p *RetryPolicy) nextDelay(ctx context.Context, attempt int, header http.Header, err error) (time.Duration, bool) {
+	if p == nil || err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+
+	// Local failures such as signing errors would fail the same way again
+	var apiErr *APIError
+	var transportErr *transportError
+	switch {
+	case errors.As(err, &apiErr):
+		if !slices.Contains(p.RetryableStatusCodes, apiErr.StatusCode) {
+			return 0, false
+		}
+		if delay, ok := parseRetryAfter(header); ok {
+			return p.capDelay(delay), true
+		}
+	case !errors.As(err, &transportErr):
+		return 0, false
+	}
+
+	// Double the backoff once per earlier retry, stopping at the cap so it can't overflow
+	maxBackoff := p.maxBackoff()
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff > 0 && backoff < maxBackoff; i++ {
+		if backoff > maxBackoff/2 {
+			backoff = maxBackoff
+		} else {
+			backoff *= 2
+		}
+	}
+	if p.Jitter > 0 {
+		backoff = time.Duration(float64(backoff) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return p.capDelay(backoff), true
+}
+
+func (p *RetryPolicy) capDelay(delay time.Duration) time.Duration {
+	return min(max(delay, 0), p.maxBackoff())
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff <= 0 {
+		return defaultMaxBackoff
+	}
+	return p.MaxBackoff
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for the given duration or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// initiationResponse is the decoded response of an initiation request. A retried initiation
+// is answered with DUPLICATE_IGNORED when an earlier attempt reached pawaPay but its response
+// was lost, so after a retry the original acceptance is reported instead.
+type initiationResponse interface {
+	acceptDuplicate()
+}
+
+// bulkPayoutResponses are the results of a bulk payout batch, one per payout
+type bulkPayoutResponses []RequestPayoutResponse
+
+func (r *RequestDepositResponse) acceptDuplicate() { acceptDuplicate(&r.Status) }
+func (r *RequestPayoutResponse) acceptDuplicate()  { acceptDuplicate(&r.Status) }
+func (r *RequestRefundResponse) acceptDuplicate()  { acceptDuplicate(&r.Status) }
+
+func (r bulkPayoutResponses) acceptDuplicate() {
+	for i := range r {
+		r[i].acceptDuplicate()
+	}
+}
+
+func acceptDuplicate(status *InitiationStatus) {
+	if *status == INITIATION_STATUS_DUPLICATE_IGNORED {
+		*status = INITIATION_STATUS_ACCEPTED
+	}
+}
@@ -0,0 +1,646 @@
+// Package sfv parses and serializes Structured Field Values for HTTP (RFC 8941), the format
+// of headers such as Signature-Input, Signature and Content-Digest.
+//
+// Bare item values are represented by these Go types:
+//
+//	Integer       int64 (int is accepted when serializing)
+//	Decimal       float64
+//	String        string
+//	Token         Token
+//	Byte Sequence []byte
+//	Boolean       bool
+//
+// Parameters and dictionaries keep their members in order, so serialization is deterministic.
+package sfv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxInteger = 999_999_999_999_999
+	maxDecimal = 999_999_999_999.999
+)
+
+// Token is a short textual word, e.g. the algorithm name in sha-512=:...:
+type Token string
+
+// Param is a single parameter of an Item or InnerList
+type Param struct {
+	Key   string
+	Value any
+}
+
+// Params are the ordered parameters of an Item or InnerList
+type Params []Param
+
+// Get returns the value of the parameter with the given key
+func (p Params) Get(key string) (any, bool) {
+	for _, param := range p {
+		if param.Key == key {
+			return param.Value, true
+		}
+	}
+	return nil, false
+}
+
+// set replaces the value of an existing parameter or appends a new one
+func (p Params) set(key string, value any) Params {
+	for i := range p {
+		if p[i].Key == key {
+			p[i].Value = value
+			return p
+		}
+	}
+	return append(p, Param{Key: key, Value: value})
+}
+
+// Member is an Item or an InnerList
+type Member interface {
+	member()
+}
+
+// Item is a bare item with parameters
+type Item struct {
+	Value  any
+	Params Params
+}
+
+// InnerList is a parenthesized list of items with parameters
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+func (Item) member()      {}
+func (InnerList) member() {}
+
+// List is a top-level list of members
+type List []Member
+
+// DictMember is a single member of a Dictionary
+type DictMember struct {
+	Key   string
+	Value Member
+}
+
+// Dictionary is an ordered map of keys to members
+type Dictionary []DictMember
+
+// Get returns the member with the given key
+func (d Dictionary) Get(key string) (Member, bool) {
+	for _, m := range d {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ParseItem parses a header value as an Item
+func ParseItem(s string) (Item, error) {
+	p := &parser{s: s}
+	p.discardSP()
+	item, err := p.parseItem()
+	if err != nil {
+		return Item{}, err
+	}
+	return item, p.end()
+}
+
+// ParseList parses a header value as a List
+func ParseList(s string) (List, error) {
+	p := &parser{s: s}
+	p.discardSP()
+
+	var list List
+	for !p.eof() {
+		member, err := p.parseItemOrInnerList()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, member)
+
+		if err := p.nextMember(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// ParseDictionary parses a header value as a Dictionary
+func ParseDictionary(s string) (Dictionary, error) {
+	p := &parser{s: s}
+	p.discardSP()
+
+	var dict Dictionary
+	for !p.eof() {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		var member Member
+		if p.peek() == '=' {
+			p.pos++
+			if member, err = p.parseItemOrInnerList(); err != nil {
+				return nil, err
+			}
+		} else {
+			params, err := p.parseParams()
+			if err != nil {
+				return nil, err
+			}
+			member = Item{Value: true, Params: params}
+		}
+		dict = dict.set(key, member)
+
+		if err := p.nextMember(); err != nil {
+			return nil, err
+		}
+	}
+	return dict, nil
+}
+
+func (d Dictionary) set(key string, value Member) Dictionary {
+	for i := range d {
+		if d[i].Key == key {
+			d[i].Value = value
+			return d
+		}
+	}
+	return append(d, DictMember{Key: key, Value: value})
+}
+
+// Serialize returns the header value of the item
+func (i Item) Serialize() (string, error) {
+	var sb strings.Builder
+	if err := writeItem(&sb, i); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Serialize returns the header value of the inner list
+func (l InnerList) Serialize() (string, error) {
+	var sb strings.Builder
+	if err := writeInnerList(&sb, l); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Serialize returns the header value of the list
+func (l List) Serialize() (string, error) {
+	var sb strings.Builder
+	for i, member := range l {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if err := writeMember(&sb, member); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+// Serialize returns the header value of the dictionary. Members with the value true
+// are serialized without a value, e.g. "a=1, b;x=2".
+func (d Dictionary) Serialize() (string, error) {
+	var sb strings.Builder
+	for i, m := range d {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if err := writeKey(&sb, m.Key); err != nil {
+			return "", err
+		}
+		if item, ok := m.Value.(Item); ok && item.Value == true {
+			if err := writeParams(&sb, item.Params); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte('=')
+		if err := writeMember(&sb, m.Value); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+func writeMember(sb *strings.Builder, member Member) error {
+	switch m := member.(type) {
+	case Item:
+		return writeItem(sb, m)
+	case InnerList:
+		return writeInnerList(sb, m)
+	}
+	return fmt.Errorf("sfv: unsupported member type %T", member)
+}
+
+func writeInnerList(sb *strings.Builder, l InnerList) error {
+	sb.WriteByte('(')
+	for i, item := range l.Items {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if err := writeItem(sb, item); err != nil {
+			return err
+		}
+	}
+	sb.WriteByte(')')
+	return writeParams(sb, l.Params)
+}
+
+func writeItem(sb *strings.Builder, i Item) error {
+	if err := writeBareItem(sb, i.Value); err != nil {
+		return err
+	}
+	return writeParams(sb, i.Params)
+}
+
+func writeParams(sb *strings.Builder, params Params) error {
+	for _, param := range params {
+		sb.WriteByte(';')
+		if err := writeKey(sb, param.Key); err != nil {
+			return err
+		}
+		if param.Value == true {
+			continue
+		}
+		sb.WriteByte('=')
+		if err := writeBareItem(sb, param.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKey(sb *strings.Builder, key string) error {
+	if key == "" || !isKeyStart(key[0]) {
+		return fmt.Errorf("sfv: invalid key %q", key)
+	}
+	for i := 1; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return fmt.Errorf("sfv: invalid key %q", key)
+		}
+	}
+	sb.WriteString(key)
+	return nil
+}
+
+func writeBareItem(sb *strings.Builder, value any) error {
+	switch v := value.(type) {
+	case int:
+		return writeBareItem(sb, int64(v))
+	case int64:
+		if v > maxInteger || v < -maxInteger {
+			return fmt.Errorf("sfv: integer %d out of range", v)
+		}
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		rounded := math.RoundToEven(v*1000) / 1000
+		if math.IsNaN(rounded) || rounded > maxDecimal || rounded < -maxDecimal {
+			return fmt.Errorf("sfv: decimal %v out of range", v)
+		}
+		s := strconv.FormatFloat(rounded, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		sb.WriteString(s)
+	case string:
+		sb.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < 0x20 || c > 0x7e {
+				return fmt.Errorf("sfv: invalid character %q in string", c)
+			}
+			if c == '"' || c == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('"')
+	case Token:
+		if v == "" || !isTokenStart(v[0]) {
+			return fmt.Errorf("sfv: invalid token %q", v)
+		}
+		for i := 1; i < len(v); i++ {
+			if !isTokenChar(v[i]) {
+				return fmt.Errorf("sfv: invalid token %q", v)
+			}
+		}
+		sb.WriteString(string(v))
+	case []byte:
+		sb.WriteByte(':')
+		sb.WriteString(base64.StdEncoding.EncodeToString(v))
+		sb.WriteByte(':')
+	case bool:
+		if v {
+			sb.WriteString("?1")
+		} else {
+			sb.WriteString("?0")
+		}
+	default:
+		return fmt.Errorf("sfv: unsupported bare item type %T", value)
+	}
+	return nil
+}
+
+// parser holds the state of parsing a single header value
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("sfv: "+format+" at offset %d", append(args, p.pos)...)
+}
+
+func (p *parser) discardSP() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) discardOWS() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// end fails when anything but trailing spaces is left
+func (p *parser) end() error {
+	p.discardSP()
+	if !p.eof() {
+		return p.errorf("unexpected %q", p.peek())
+	}
+	return nil
+}
+
+// nextMember consumes the comma between two members of a list or dictionary
+func (p *parser) nextMember() error {
+	p.discardOWS()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != ',' {
+		return p.errorf("expected ',' but found %q", p.peek())
+	}
+	p.pos++
+	p.discardOWS()
+	if p.eof() {
+		return p.errorf("trailing comma")
+	}
+	return nil
+}
+
+func (p *parser) parseItemOrInnerList() (Member, error) {
+	if p.peek() == '(' {
+		return p.parseInnerList()
+	}
+	return p.parseItem()
+}
+
+func (p *parser) parseInnerList() (InnerList, error) {
+	p.pos++ // (
+
+	var list InnerList
+	for !p.eof() {
+		p.discardSP()
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.parseParams()
+			if err != nil {
+				return InnerList{}, err
+			}
+			list.Params = params
+			return list, nil
+		}
+
+		item, err := p.parseItem()
+		if err != nil {
+			return InnerList{}, err
+		}
+		list.Items = append(list.Items, item)
+
+		if c := p.peek(); c != ' ' && c != ')' {
+			return InnerList{}, p.errorf("expected ' ' or ')' in inner list")
+		}
+	}
+	return InnerList{}, p.errorf("unterminated inner list")
+}
+
+func (p *parser) parseItem() (Item, error) {
+	value, err := p.parseBareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	params, err := p.parseParams()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: value, Params: params}, nil
+}
+
+func (p *parser) parseParams() (Params, error) {
+	var params Params
+	for p.peek() == ';' {
+		p.pos++
+		p.discardSP()
+
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		var value any = true
+		if p.peek() == '=' {
+			p.pos++
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = params.set(key, value)
+	}
+	return params, nil
+}
+
+func (p *parser) parseKey() (string, error) {
+	if !isKeyStart(p.peek()) {
+		return "", p.errorf("invalid key")
+	}
+	start := p.pos
+	for !p.eof() && isKeyChar(p.peek()) {
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *parser) parseBareItem() (any, error) {
+	switch c := p.peek(); {
+	case c == '-' || isDigit(c):
+		return p.parseNumber()
+	case c == '"':
+		return p.parseString()
+	case isTokenStart(c):
+		return p.parseToken(), nil
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		return p.parseBoolean()
+	}
+	return nil, p.errorf("unexpected %q", p.peek())
+}
+
+func (p *parser) parseNumber() (any, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("expected digit")
+	}
+
+	decimal, digits, fraction := false, 0, 0
+	for !p.eof() {
+		c := p.peek()
+		if isDigit(c) {
+			if decimal {
+				fraction++
+			} else {
+				digits++
+			}
+		} else if c == '.' && !decimal {
+			if digits > 12 {
+				return nil, p.errorf("decimal has too many integer digits")
+			}
+			decimal = true
+		} else {
+			break
+		}
+		p.pos++
+	}
+
+	s := p.s[start:p.pos]
+	if !decimal {
+		if digits > 15 {
+			return nil, p.errorf("integer has too many digits")
+		}
+		return strconv.ParseInt(s, 10, 64)
+	}
+	if fraction == 0 || fraction > 3 {
+		return nil, p.errorf("decimal must have 1 to 3 fractional digits")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func (p *parser) parseString() (string, error) {
+	p.pos++ // "
+
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if next := p.peek(); next == '"' || next == '\\' {
+				sb.WriteByte(next)
+				p.pos++
+				continue
+			}
+			return "", p.errorf("invalid escape in string")
+		case c == '"':
+			return sb.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid character %q in string", c)
+		}
+		sb.WriteByte(c)
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) parseToken() Token {
+	start := p.pos
+	p.pos++
+	for !p.eof() && isTokenChar(p.peek()) {
+		p.pos++
+	}
+	return Token(p.s[start:p.pos])
+}
+
+func (p *parser) parseByteSequence() ([]byte, error) {
+	p.pos++ // :
+
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+	encoded := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+
+	// Padding is optional when parsing
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, fmt.Errorf("sfv: invalid byte sequence: %w", err)
+	}
+	return decoded, nil
+}
+
+func (p *parser) parseBoolean() (bool, error) {
+	p.pos++ // ?
+
+	switch p.peek() {
+	case '1':
+		p.pos++
+		return true, nil
+	case '0':
+		p.pos++
+		return false, nil
+	}
+	return false, p.errorf("invalid boolean")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isKeyStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || c == '*'
+}
+
+func isKeyChar(c byte) bool {
+	return isKeyStart(c) || isDigit(c) || c == '_' || c == '-' || c == '.'
+}
+
+func isTokenStart(c byte) bool {
+	return isAlpha(c) || c == '*'
+}
+
+// isTokenChar reports whether c is a tchar (RFC 9110) or one of ':' and '/'
+func isTokenChar(c byte) bool {
+	if isAlpha(c) || isDigit(c) {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
@@ -0,0 +1,202 @@
+package sfv
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestParseDictionary_SignatureInput tests parsing a Signature-Input header
+func TestParseDictionary_SignatureInput(t *testing.T) {
+	header := `sig-pp=("@method" "@authority" "@query-param";name="id" "content-digest");alg="rsa-pss-sha512";created=1735689600;keyid="key-1", sig-b=("@method");created=1`
+
+	dict, err := ParseDictionary(header)
+	if err != nil {
+		t.Fatalf("ParseDictionary failed: %v", err)
+	}
+	if len(dict) != 2 || dict[0].Key != "sig-pp" || dict[1].Key != "sig-b" {
+		t.Fatalf("Expected members sig-pp and sig-b in order, got %+v", dict)
+	}
+
+	member, _ := dict.Get("sig-pp")
+	list, ok := member.(InnerList)
+	if !ok {
+		t.Fatalf("Expected InnerList, got %T", member)
+	}
+	if len(list.Items) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(list.Items))
+	}
+	if list.Items[2].Value != "@query-param" {
+		t.Errorf("Expected @query-param, got %v", list.Items[2].Value)
+	}
+	if name, _ := list.Items[2].Params.Get("name"); name != "id" {
+		t.Errorf("Expected name parameter id, got %v", name)
+	}
+	if alg, _ := list.Params.Get("alg"); alg != "rsa-pss-sha512" {
+		t.Errorf("Expected alg rsa-pss-sha512, got %v", alg)
+	}
+	if created, _ := list.Params.Get("created"); created != int64(1735689600) {
+		t.Errorf("Expected created 1735689600, got %v", created)
+	}
+
+	serialized, err := dict.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if serialized != header {
+		t.Errorf("Expected round trip to %q, got %q", header, serialized)
+	}
+}
+
+// TestParseDictionary_ByteSequence tests parsing Signature and Content-Digest headers
+func TestParseDictionary_ByteSequence(t *testing.T) {
+	dict, err := ParseDictionary("sha-256=:AQID:, sha-512=:BAUG:")
+	if err != nil {
+		t.Fatalf("ParseDictionary failed: %v", err)
+	}
+
+	member, ok := dict.Get("sha-512")
+	if !ok {
+		t.Fatal("Expected sha-512 member")
+	}
+	if value := member.(Item).Value; !bytes.Equal(value.([]byte), []byte{4, 5, 6}) {
+		t.Errorf("Expected bytes 040506, got %v", value)
+	}
+}
+
+// TestParseDictionary_BooleanMembers tests dictionary members without a value
+func TestParseDictionary_BooleanMembers(t *testing.T) {
+	dict, err := ParseDictionary("a=?0, b, c;foo=bar")
+	if err != nil {
+		t.Fatalf("ParseDictionary failed: %v", err)
+	}
+
+	if value := dict[1].Value.(Item).Value; value != true {
+		t.Errorf("Expected b to be true, got %v", value)
+	}
+	if foo, _ := dict[2].Value.(Item).Params.Get("foo"); foo != Token("bar") {
+		t.Errorf("Expected token bar, got %v", foo)
+	}
+
+	serialized, _ := dict.Serialize()
+	if serialized != "a=?0, b, c;foo=bar" {
+		t.Errorf("Expected a=?0, b, c;foo=bar, got %s", serialized)
+	}
+}
+
+// TestParseItem tests parsing bare items of every type
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{"42", int64(42)},
+		{"-17", int64(-17)},
+		{"4.5", 4.5},
+		{`"hello \"world\""`, `hello "world"`},
+		{"foo/bar:baz", Token("foo/bar:baz")},
+		{"?1", true},
+		{"?0", false},
+	}
+
+	for _, tt := range tests {
+		item, err := ParseItem(tt.input)
+		if err != nil {
+			t.Errorf("ParseItem(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if item.Value != tt.want {
+			t.Errorf("ParseItem(%q): expected %v, got %v", tt.input, tt.want, item.Value)
+		}
+	}
+}
+
+// TestParse_Invalid tests that malformed values are rejected
+func TestParse_Invalid(t *testing.T) {
+	invalid := []string{
+		`"unterminated`,
+		`(a b`,
+		`a, `,
+		`1.2345`,
+		`1234567890123456`,
+		`"bad \x"`,
+		`?2`,
+		`:AQID`,
+		`A=1`,
+		`a=1 b=2`,
+	}
+
+	for _, input := range invalid {
+		if _, err := ParseDictionary(input); err == nil {
+			t.Errorf("Expected error for %q, got nil", input)
+		}
+	}
+}
+
+// TestParseList tests parsing a list with items and inner lists
+func TestParseList(t *testing.T) {
+	list, err := ParseList(`sugar, tea, (rum "milk");x=1`)
+	if err != nil {
+		t.Fatalf("ParseList failed: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 members, got %d", len(list))
+	}
+
+	serialized, _ := list.Serialize()
+	if serialized != `sugar, tea, (rum "milk");x=1` {
+		t.Errorf("Unexpected serialization %s", serialized)
+	}
+}
+
+// TestSerialize_ParameterOrder tests that parameters are serialized in the order they were given
+func TestSerialize_ParameterOrder(t *testing.T) {
+	item := Item{Value: "example-dict", Params: Params{{Key: "sf", Value: true}, {Key: "key", Value: "a"}}}
+
+	for i := 0; i < 10; i++ {
+		serialized, err := item.Serialize()
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		if serialized != `"example-dict";sf;key="a"` {
+			t.Fatalf(`Expected "example-dict";sf;key="a", got %s`, serialized)
+		}
+	}
+}
+
+// TestSerialize_Invalid tests that values outside the RFC 8941 ranges are rejected
+func TestSerialize_Invalid(t *testing.T) {
+	invalid := []Item{
+		{Value: int64(1_000_000_000_000_000)},
+		{Value: "new\nline"},
+		{Value: Token("1abc")},
+		{Value: struct{}{}},
+		{Value: true, Params: Params{{Key: "Upper", Value: true}}},
+	}
+
+	for _, item := range invalid {
+		if _, err := item.Serialize(); err == nil {
+			t.Errorf("Expected error for %+v, got nil", item)
+		}
+	}
+}
+
+// TestSerialize_Decimal tests decimal rounding
+func TestSerialize_Decimal(t *testing.T) {
+	tests := map[float64]string{
+		1:        "1.0",
+		1.5:      "1.5",
+		1.0004:   "1.0",
+		-2.25:    "-2.25",
+		123.4567: "123.457",
+	}
+
+	for value, want := range tests {
+		serialized, err := Item{Value: value}.Serialize()
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		if serialized != want {
+			t.Errorf("Expected %s for %v, got %s", want, value, serialized)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package pawapaygo
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/salticon/pawapay-go-sdk/sfv"
+)
+
+// Signature algorithms defined by RFC 9421
+const (
+	SIGNATURE_ALG_RSA_PSS_SHA512    = "rsa-pss-sha512"
+	SIGNATURE_ALG_RSA_V1_5_SHA256   = "rsa-v1_5-sha256"
+	SIGNATURE_ALG_ECDSA_P256_SHA256 = "ecdsa-p256-sha256"
+	SIGNATURE_ALG_ECDSA_P384_SHA384 = "ecdsa-p384-sha384"
+	SIGNATURE_ALG_ED25519           = "ed25519"
+)
+
+// signatureLabel is the label pawaPay uses in the Signature and Signature-Input headers
+const signatureLabel = "sig-pp"
+
+const defaultSignatureExpiry = 60 * time.Second
+
+// DefaultSignedComponents are the request components covered by the signature of a RequestSigner
+var DefaultSignedComponents = []Component{
+	{Name: "@method"},
+	{Name: "@authority"},
+	{Name: "@path"},
+	{Name: "signature-date"},
+	{Name: "content-digest"},
+	{Name: "content-type"},
+}
+
+// RequestSigner signs outgoing requests according to RFC 9421 (HTTP Message Signatures).
+// It is required for accounts with SignatureConfiguration.SignedRequestsOnly enabled.
+type RequestSigner struct {
+	// KeyID identifies the public key registered with pawaPay
+	KeyID string
+	// Expiry is how long a signature stays valid after it is created. Defaults to 60 seconds.
+	Expiry time.Duration
+	// Components covered by the signature. Defaults to DefaultSignedComponents.
+	Components []Component
+
+	key crypto.Signer
+	alg string
+}
+
+// NewRequestSigner creates a RequestSigner from a PEM encoded RSA, ECDSA (P-256 or P-384)
+// or Ed25519 private key. RSA keys sign with rsa-pss-sha512.
+func NewRequestSigner(keyID string, privateKeyPEM []byte) (*RequestSigner, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("keyID is required")
+	}
+
+	key, err := ParsePrivateKeyPEM(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := signatureAlgorithm(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestSigner{
+		KeyID: keyID,
+		key:   key,
+		alg:   alg,
+	}, nil
+}
+
+// ParsePrivateKeyPEM parses a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key in PEM format
+func ParsePrivateKeyPEM(privateKeyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in private key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("failed to parse private key of type %q", block.Type)
+}
+
+// Alg returns the RFC 9421 algorithm used by the signer
+func (s *RequestSigner) Alg() string {
+	return s.alg
+}
+
+// Sign adds the Content-Digest, Signature-Date, Signature-Input and Signature headers to req.
+// body must be the exact bytes sent as the request body.
+func (s *RequestSigner) Sign(req *http.Request, body []byte) error {
+	now := time.Now()
+
+	expiry := s.Expiry
+	if expiry == 0 {
+		expiry = defaultSignatureExpiry
+	}
+
+	components := s.Components
+	if components == nil {
+		components = DefaultSignedComponents
+	}
+
+	req.Header.Set("Content-Digest", CreateContentDigestHeader(body))
+	req.Header.Set("Signature-Date", now.UTC().Format(time.RFC3339))
+
+	signatureBase, signatureInput, err := CreateSignatureBase(req, body, SignatureParams{
+		Components: components,
+		Alg:        s.alg,
+		Created:    now.Unix(),
+		Expires:    now.Add(expiry).Unix(),
+		KeyID:      s.KeyID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create signature base: %w", err)
+	}
+
+	signature, err := signMessage(s.alg, s.key, []byte(signatureBase))
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	req.Header.Set("Signature-Input", signatureLabel+"="+signatureInput)
+	signatureHeader, err := sfv.Dictionary{{Key: signatureLabel, Value: sfv.Item{Value: signature}}}.Serialize()
+	if err != nil {
+		return fmt.Errorf("failed to serialize signature: %w", err)
+	}
+	req.Header.Set("Signature", signatureHeader)
+
+	return nil
+}
+
+// signatureAlgorithm picks the RFC 9421 algorithm matching the type of the key
+func signatureAlgorithm(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return SIGNATURE_ALG_RSA_PSS_SHA512, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return SIGNATURE_ALG_ECDSA_P256_SHA256, nil
+		case elliptic.P384():
+			return SIGNATURE_ALG_ECDSA_P384_SHA384, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return SIGNATURE_ALG_ED25519, nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+// signMessage signs the signature base with the given RFC 9421 algorithm
+func signMessage(alg string, key crypto.Signer, message []byte) ([]byte, error) {
+	switch alg {
+	case SIGNATURE_ALG_RSA_PSS_SHA512:
+		digest := sha512.Sum512(message)
+		return key.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	case SIGNATURE_ALG_RSA_V1_5_SHA256:
+		digest := sha256.Sum256(message)
+		return key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case SIGNATURE_ALG_ECDSA_P256_SHA256:
+		digest := sha256.Sum256(message)
+		return signECDSA(key, digest[:], 32)
+	case SIGNATURE_ALG_ECDSA_P384_SHA384:
+		digest := sha512.Sum384(message)
+		return signECDSA(key, digest[:], 48)
+	case SIGNATURE_ALG_ED25519:
+		return key.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	return nil, fmt.Errorf("unsupported signature algorithm %q", alg)
+}
+
+// signECDSA returns the signature as the fixed size concatenation of r and s required by RFC 9421
+func signECDSA(key crypto.Signer, digest []byte, size int) ([]byte, error) {
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ECDSA private key, got %T", key)
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature, nil
+}
+
+// verifyMessage verifies a signature created with the given RFC 9421 algorithm
+func verifyMessage(alg string, key crypto.PublicKey, message, signature []byte) error {
+	switch alg {
+	case SIGNATURE_ALG_RSA_PSS_SHA512:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires an RSA key, got %T", alg, key)
+		}
+		digest := sha512.Sum512(message)
+		return rsa.VerifyPSS(rsaKey, crypto.SHA512, digest[:], signature, &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	case SIGNATURE_ALG_RSA_V1_5_SHA256:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires an RSA key, got %T", alg, key)
+		}
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature)
+	case SIGNATURE_ALG_ECDSA_P256_SHA256:
+		digest := sha256.Sum256(message)
+		return verifyECDSA(alg, key, digest[:], signature, 32)
+	case SIGNATURE_ALG_ECDSA_P384_SHA384:
+		digest := sha512.Sum384(message)
+		return verifyECDSA(alg, key, digest[:], signature, 48)
+	case SIGNATURE_ALG_ED25519:
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s requires an Ed25519 key, got %T", alg, key)
+		}
+		if !ed25519.Verify(edKey, message, signature) {
+			return fmt.Errorf("ed25519 signature mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signature algorithm %q", alg)
+}
+
+func verifyECDSA(alg string, key crypto.PublicKey, digest, signature []byte, size int) error {
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("algorithm %s requires an ECDSA key, got %T", alg, key)
+	}
+	if len(signature) != 2*size {
+		return fmt.Errorf("invalid %s signature length %d", alg, len(signature))
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(ecKey, digest, r, s) {
+		return fmt.Errorf("ecdsa signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package pawapaygo
+
+import "fmt"
+
+// DepositStatus is the status of a deposit, as returned by GetDepositStatus and sent in
+// deposit callbacks. Values that aren't known to this SDK are decoded as they are, so new
+// statuses added by pawaPay don't break decoding; IsKnown reports them.
+type DepositStatus string
+
+const (
+	DEPOSIT_STATUS_ACCEPTED          DepositStatus = "ACCEPTED"
+	DEPOSIT_STATUS_ENQUEUED          DepositStatus = "ENQUEUED"
+	DEPOSIT_STATUS_SUBMITTED         DepositStatus = "SUBMITTED"
+	DEPOSIT_STATUS_PROCESSING        DepositStatus = "PROCESSING"
+	DEPOSIT_STATUS_IN_RECONCILIATION DepositStatus = "IN_RECONCILIATION"
+	DEPOSIT_STATUS_COMPLETED         DepositStatus = "COMPLETED"
+	DEPOSIT_STATUS_FAILED            DepositStatus = "FAILED"
+	DEPOSIT_STATUS_REJECTED          DepositStatus = "REJECTED"
+)
+
+// depositTransitions lists the statuses a deposit can move to from each non-final status
+var depositTransitions = map[DepositStatus][]DepositStatus{
+	DEPOSIT_STATUS_ACCEPTED: {
+		DEPOSIT_STATUS_ENQUEUED, DEPOSIT_STATUS_SUBMITTED, DEPOSIT_STATUS_PROCESSING,
+		DEPOSIT_STATUS_IN_RECONCILIATION, DEPOSIT_STATUS_COMPLETED, DEPOSIT_STATUS_FAILED,
+	},
+	DEPOSIT_STATUS_ENQUEUED: {
+		DEPOSIT_STATUS_SUBMITTED, DEPOSIT_STATUS_PROCESSING, DEPOSIT_STATUS_IN_RECONCILIATION,
+		DEPOSIT_STATUS_COMPLETED, DEPOSIT_STATUS_FAILED,
+	},
+	DEPOSIT_STATUS_SUBMITTED: {
+		DEPOSIT_STATUS_PROCESSING, DEPOSIT_STATUS_IN_RECONCILIATION, DEPOSIT_STATUS_COMPLETED,
+		DEPOSIT_STATUS_FAILED,
+	},
+	DEPOSIT_STATUS_PROCESSING: {
+		DEPOSIT_STATUS_IN_RECONCILIATION, DEPOSIT_STATUS_COMPLETED, DEPOSIT_STATUS_FAILED,
+	},
+	DEPOSIT_STATUS_IN_RECONCILIATION: {
+		DEPOSIT_STATUS_COMPLETED, DEPOSIT_STATUS_FAILED,
+	},
+	DEPOSIT_STATUS_COMPLETED: nil,
+	DEPOSIT_STATUS_FAILED:    nil,
+	DEPOSIT_STATUS_REJECTED:  nil,
+}
+
+// IsKnown reports whether s is one of the DEPOSIT_STATUS constants
+func (s DepositStatus) IsKnown() bool {
+	_, ok := depositTransitions[s]
+	return ok
+}
+
+// IsFinal reports whether the deposit won't change anymore: COMPLETED, FAILED or REJECTED
+func (s DepositStatus) IsFinal() bool {
+	return s == DEPOSIT_STATUS_COMPLETED || s == DEPOSIT_STATUS_FAILED || s == DEPOSIT_STATUS_REJECTED
+}
+
+// IsSuccess reports whether the funds were collected
+func (s DepositStatus) IsSuccess() bool {
+	return s == DEPOSIT_STATUS_COMPLETED
+}
+
+// ValidateTransition returns an error when a deposit can't move from s to next, e.g. from
+// COMPLETED to FAILED. Staying in the same status is valid, since callbacks and status polls
+// repeat it. Transitions from or to unknown statuses are only rejected when s is final.
+func (s DepositStatus) ValidateTransition(next DepositStatus) error {
+	if s == next {
+		return nil
+	}
+	if s.IsFinal() {
+		return fmt.Errorf("invalid deposit status transition from final status %s to %s", s, next)
+	}
+	if !s.IsKnown() || !next.IsKnown() {
+		return nil
+	}
+
+	for _, allowed := range depositTransitions[s] {
+		if next == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid deposit status transition from %s to %s", s, next)
+}
+
+// PayoutStatus is the status of a payout, as returned by GetPayoutStatus and sent in payout
+// callbacks. Like DepositStatus, unknown values are decoded as they are.
+type PayoutStatus string
+
+const (
+	PAYOUT_STATUS_ACCEPTED          PayoutStatus = "ACCEPTED"
+	PAYOUT_STATUS_ENQUEUED          PayoutStatus = "ENQUEUED"
+	PAYOUT_STATUS_PROCESSING        PayoutStatus = "PROCESSING"
+	PAYOUT_STATUS_IN_RECONCILIATION PayoutStatus = "IN_RECONCILIATION"
+	PAYOUT_STATUS_COMPLETED         PayoutStatus = "COMPLETED"
+	PAYOUT_STATUS_FAILED            PayoutStatus = "FAILED"
+)
+
+// IsKnown reports whether s is one of the PAYOUT_STATUS constants
+func (s PayoutStatus) IsKnown() bool {
+	switch s {
+	case PAYOUT_STATUS_ACCEPTED, PAYOUT_STATUS_ENQUEUED, PAYOUT_STATUS_PROCESSING,
+		PAYOUT_STATUS_IN_RECONCILIATION, PAYOUT_STATUS_COMPLETED, PAYOUT_STATUS_FAILED:
+		return true
+	}
+	return false
+}
+
+// IsFinal reports whether the payout won't change anymore: COMPLETED or FAILED
+func (s PayoutStatus) IsFinal() bool {
+	return s == PAYOUT_STATUS_COMPLETED || s == PAYOUT_STATUS_FAILED
+}
+
+// IsSuccess reports whether the funds were disbursed
+func (s PayoutStatus) IsSuccess() bool {
+	return s == PAYOUT_STATUS_COMPLETED
+}
+
+// RefundStatus is the status of a refund, as returned by GetRefundStatus and sent in refund
+// callbacks. Like DepositStatus, unknown values are decoded as they are.
+type RefundStatus string
+
+const (
+	REFUND_STATUS_ACCEPTED          RefundStatus = "ACCEPTED"
+	REFUND_STATUS_PROCESSING        RefundStatus = "PROCESSING"
+	REFUND_STATUS_IN_RECONCILIATION RefundStatus = "IN_RECONCILIATION"
+	REFUND_STATUS_COMPLETED         RefundStatus = "COMPLETED"
+	REFUND_STATUS_FAILED            RefundStatus = "FAILED"
+)
+
+// IsKnown reports whether s is one of the REFUND_STATUS constants
+func (s RefundStatus) IsKnown() bool {
+	switch s {
+	case REFUND_STATUS_ACCEPTED, REFUND_STATUS_PROCESSING, REFUND_STATUS_IN_RECONCILIATION,
+		REFUND_STATUS_COMPLETED, REFUND_STATUS_FAILED:
+		return true
+	}
+	return false
+}
+
+// IsFinal reports whether the refund won't change anymore: COMPLETED or FAILED
+func (s RefundStatus) IsFinal() bool {
+	return s == REFUND_STATUS_COMPLETED || s == REFUND_STATUS_FAILED
+}
+
+// IsSuccess reports whether the funds were refunded
+func (s RefundStatus) IsSuccess() bool {
+	return s == REFUND_STATUS_COMPLETED
+}
+
+// InitiationStatus is the status of an initiation request such as InitiateDeposit,
+// InitiatePayout or InitiateRefund. Requests that can only be accepted or rejected, such as
+// CancelEnqueuedPayout and the resend-callback requests, use it as well.
+type InitiationStatus string
+
+const (
+	INITIATION_STATUS_ACCEPTED          InitiationStatus = "ACCEPTED"
+	INITIATION_STATUS_REJECTED          InitiationStatus = "REJECTED"
+	INITIATION_STATUS_DUPLICATE_IGNORED InitiationStatus = "DUPLICATE_IGNORED"
+)
+
+// IsKnown reports whether s is one of the INITIATION_STATUS constants
+func (s InitiationStatus) IsKnown() bool {
+	return s == INITIATION_STATUS_ACCEPTED || s == INITIATION_STATUS_REJECTED || s == INITIATION_STATUS_DUPLICATE_IGNORED
+}
+
+// IsSuccess reports whether pawaPay accepted the request for processing. DUPLICATE_IGNORED
+// is not a success: the ID was already used by an earlier request, whose status has to be
+// checked instead. A retry of a request whose first attempt reached pawaPay is reported as
+// ACCEPTED rather than DUPLICATE_IGNORED, see RetryPolicy.
+func (s InitiationStatus) IsSuccess() bool {
+	return s == INITIATION_STATUS_ACCEPTED
+}
+
+// LookupStatus is the status of a status check such as GetDepositStatus or GetPayoutStatus
+type LookupStatus string
+
+const (
+	LOOKUP_STATUS_FOUND     LookupStatus = "FOUND"
+	LOOKUP_STATUS_NOT_FOUND LookupStatus = "NOT_FOUND"
+)
+
+// IsKnown reports whether s is one of the LOOKUP_STATUS constants
+func (s LookupStatus) IsKnown() bool {
+	return s == LOOKUP_STATUS_FOUND || s == LOOKUP_STATUS_NOT_FOUND
+}
+
+// IsFound reports whether the transaction was found
+func (s LookupStatus) IsFound() bool {
+	return s == LOOKUP_STATUS_FOUND
+}
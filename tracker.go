@@ -0,0 +1,266 @@
+package pawapaygo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTrackerPollInterval    = 5 * time.Second
+	defaultTrackerMaxPollInterval = time.Minute
+	defaultTrackerNotFoundPeriod  = time.Minute
+	defaultTrackerTimeout         = 15 * time.Minute
+	defaultTrackerResultRetention = 5 * time.Minute
+)
+
+// DepositResult is the outcome of tracking a deposit. Deposit is set once the deposit
+// reached a final status, Err when tracking failed.
+type DepositResult struct {
+	Deposit *DepositData
+	Err     error
+}
+
+// DepositTracker follows deposits until they reach a final status (COMPLETED, FAILED or
+// REJECTED). The final status is taken from whichever comes first: a callback passed to
+// HandleCallback, or polling GetDepositStatus with exponential backoff, which covers
+// callbacks that are delayed or lost. It is safe for concurrent use.
+//
+// Configure the fields before the first deposit is tracked.
+type DepositTracker struct {
+	// PollInterval is the delay before the first status poll. It doubles after every poll
+	// that doesn't find a final status. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// MaxPollInterval caps the delay between two polls. Defaults to 1 minute.
+	MaxPollInterval time.Duration
+	// NotFoundPeriod is how long NOT_FOUND is accepted after tracking started, since a new
+	// deposit may not be visible to the status endpoint right away. Defaults to 1 minute.
+	NotFoundPeriod time.Duration
+	// Timeout ends tracking of a deposit that doesn't reach a final status. Defaults to 15 minutes.
+	Timeout time.Duration
+	// ResultRetention is how long the final status of a deposit is kept, so WaitForFinal and
+	// Subscribe return it right away when the deposit finished before they were called, e.g.
+	// when its callback arrived first. Defaults to 5 minutes.
+	ResultRetention time.Duration
+
+	client PawapayAPIClient
+
+	mu       sync.Mutex
+	deposits map[string]*trackedDeposit
+}
+
+// trackedDeposit is a deposit that is waiting for its final status, or that reached it
+// within ResultRetention
+type trackedDeposit struct {
+	done     chan struct{}
+	result   DepositResult
+	finished bool
+	cancel   context.CancelFunc // Stops polling
+
+	waiters int  // Calls of WaitForFinal and subscriptions waiting for the result
+	pinned  bool // Tracked with Track, so polling continues without waiters
+}
+
+// complete records the result and stops polling. It reports false when the deposit already had a result.
+func (d *trackedDeposit) complete(result DepositResult) bool {
+	if d.finished {
+		return false
+	}
+	d.finished = true
+	d.result = result
+	close(d.done)
+	d.cancel()
+	return true
+}
+
+// NewDepositTracker creates a DepositTracker that polls deposit statuses with client
+func NewDepositTracker(client PawapayAPIClient) *DepositTracker {
+	return &DepositTracker{
+		client:   client,
+		deposits: make(map[string]*trackedDeposit),
+	}
+}
+
+// Track starts following a deposit, typically right after InitiateDeposit. The deposit is
+// polled until it reaches a final status, Timeout passes or Untrack is called, even when
+// nobody waits for it. Its final status is kept for ResultRetention. Tracking a deposit that
+// is already tracked has no effect.
+func (t *DepositTracker) Track(depositID string) {
+	t.track(depositID, false)
+}
+
+// Untrack stops tracking a deposit and forgets its final status. Calls of WaitForFinal and
+// subscriptions waiting for it get an error matching context.Canceled.
+func (t *DepositTracker) Untrack(depositID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if d, ok := t.deposits[depositID]; ok {
+		delete(t.deposits, depositID)
+		d.complete(DepositResult{Err: fmt.Errorf("deposit %s is no longer tracked: %w", depositID, context.Canceled)})
+	}
+}
+
+// WaitForFinal tracks the deposit and blocks until it reaches a final status, tracking
+// fails or ctx is done. Polling stops when no caller waits for the deposit anymore, unless
+// it was tracked with Track.
+func (t *DepositTracker) WaitForFinal(ctx context.Context, depositID string) (*DepositData, error) {
+	d := t.track(depositID, true)
+	defer t.release(depositID, d)
+
+	select {
+	case <-d.done:
+		return d.result.Deposit, d.result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Subscribe tracks the deposit and returns a channel that receives its result once and is
+// then closed. When ctx is done first, the channel is closed without a result and polling
+// stops like for WaitForFinal.
+func (t *DepositTracker) Subscribe(ctx context.Context, depositID string) <-chan DepositResult {
+	d := t.track(depositID, true)
+
+	ch := make(chan DepositResult, 1)
+	go func() {
+		defer t.release(depositID, d)
+
+		select {
+		case <-d.done:
+			ch <- d.result
+		case <-ctx.Done():
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// HandleCallback completes tracking of a deposit with the status from its callback. It can
+// be used as WebhookHandler.OnDepositCompleted and OnDepositFailed. Callbacks of deposits
+// that aren't tracked are ignored.
+func (t *DepositTracker) HandleCallback(_ context.Context, callback *DepositCallback) error {
+	if callback.Status.IsFinal() {
+		t.finish(callback.DepositID, DepositResult{Deposit: callback})
+	}
+	return nil
+}
+
+// track starts polling the deposit unless it is already tracked, and registers a waiter or pins it
+func (t *DepositTracker) track(depositID string, waiting bool) *trackedDeposit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.deposits[depositID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		d = &trackedDeposit{done: make(chan struct{}), cancel: cancel}
+		t.deposits[depositID] = d
+		go t.poll(ctx, depositID, d)
+	}
+
+	if waiting {
+		d.waiters++
+	} else {
+		d.pinned = true
+	}
+	return d
+}
+
+// release unregisters a waiter and stops polling when it was the last one of an unpinned deposit
+func (t *DepositTracker) release(depositID string, d *trackedDeposit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d.waiters--
+	if d.waiters == 0 && !d.pinned && !d.finished && t.deposits[depositID] == d {
+		delete(t.deposits, depositID)
+		d.cancel()
+	}
+}
+
+// finish records the result of a tracked deposit and stops polling it. Only the first result
+// counts. A final status is kept for ResultRetention; after errors the deposit is forgotten
+// right away, so it can be tracked again.
+func (t *DepositTracker) finish(depositID string, result DepositResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.deposits[depositID]
+	if !ok || !d.complete(result) {
+		return
+	}
+	if result.Deposit == nil {
+		delete(t.deposits, depositID)
+		return
+	}
+
+	time.AfterFunc(valueOrDefault(t.ResultRetention, defaultTrackerResultRetention), func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if t.deposits[depositID] == d {
+			delete(t.deposits, depositID)
+		}
+	})
+}
+
+// poll queries the deposit status with exponential backoff until the deposit is finished or
+// ctx is cancelled
+func (t *DepositTracker) poll(ctx context.Context, depositID string, d *trackedDeposit) {
+	timeout := valueOrDefault(t.Timeout, defaultTrackerTimeout)
+	interval := valueOrDefault(t.PollInterval, defaultTrackerPollInterval)
+	maxInterval := valueOrDefault(t.MaxPollInterval, defaultTrackerMaxPollInterval)
+	notFoundPeriod := valueOrDefault(t.NotFoundPeriod, defaultTrackerNotFoundPeriod)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-d.done:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			// Polling was stopped because nobody waits for the deposit anymore
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return
+			}
+			t.finish(depositID, DepositResult{Err: fmt.Errorf("deposit %s did not reach a final status within %s", depositID, timeout)})
+			return
+		case <-timer.C:
+		}
+
+		response, err := t.client.GetDepositStatusContext(ctx, depositID)
+		switch {
+		case err != nil:
+			// Connection errors, rate limits and server errors are retried with the next poll
+			if errors.Is(err, ErrAuthentication) || errors.Is(err, ErrInvalidInput) {
+				t.finish(depositID, DepositResult{Err: err})
+				return
+			}
+		case !response.Status.IsFound() || response.Data == nil:
+			if time.Since(started) > notFoundPeriod {
+				t.finish(depositID, DepositResult{Err: fmt.Errorf("deposit %s: %w", depositID, ErrNotFound)})
+				return
+			}
+		case response.Data.Status.IsFinal():
+			t.finish(depositID, DepositResult{Deposit: response.Data})
+			return
+		}
+
+		interval = min(interval*2, maxInterval)
+	}
+}
+
+func valueOrDefault(d, defaultValue time.Duration) time.Duration {
+	if d == 0 {
+		return defaultValue
+	}
+	return d
+}
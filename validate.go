@@ -0,0 +1,232 @@
+package pawapaygo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Values of OperationType.Status
+const (
+	OPERATION_STATUS_OPERATIONAL = "OPERATIONAL"
+	OPERATION_STATUS_DELAYED     = "DELAYED"
+	OPERATION_STATUS_CLOSED      = "CLOSED"
+)
+
+const (
+	// How long a Client with ValidateRequests caches the active configuration
+	activeConfigurationTTL = time.Hour
+	// Minimum time between two fetches of the active configuration after a failed one
+	activeConfigurationRetryInterval = time.Minute
+)
+
+var (
+	uuidPattern            = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	phoneNumberPattern     = regexp.MustCompile(`^[0-9]+$`)
+	customerMessagePattern = regexp.MustCompile(`^[a-zA-Z0-9 ]{4,22}$`)
+)
+
+// FieldError describes an invalid field of a request
+type FieldError struct {
+	Field   string // JSON path of the field, e.g. "payer.accountDetails.provider"
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors lists the invalid fields of a request. It is returned by the Validate
+// methods of request bodies and matches ErrInvalidInput with errors.Is.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// Is reports whether target is ErrInvalidInput
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// add records an invalid field
+func (e *ValidationErrors) add(field, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns e as an error, or nil when no field is invalid
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks the deposit before it is sent, so requests pawaPay would reject right
+// away fail without a network call. The depositId must be a UUID, the amount must be in a
+// valid format and customerMessage must have 4 to 22 letters, digits or spaces. When cfg is
+// set, the provider must support the currency for deposits and not be CLOSED, the amount
+// must be within its limits and the phone number must start with the country prefix.
+// The returned error is a ValidationErrors.
+func (i *InitiateDepositRequestBody) Validate(cfg *ActiveConfigurationResponse) error {
+	var errs ValidationErrors
+	validateUUID(&errs, "depositId", i.DepositID)
+	validateCustomerMessage(&errs, i.CustomerMessage)
+	validateTransaction(&errs, cfg, OPERATION_TYPE_DEPOSIT, "payer.accountDetails", i.Payer.AccountDetails, i.Amount, i.Currency)
+	return errs.err()
+}
+
+// Validate checks the payout before it is sent, like InitiateDepositRequestBody.Validate
+// does for deposits. The returned error is a ValidationErrors.
+func (i *InitiatePayoutRequestBody) Validate(cfg *ActiveConfigurationResponse) error {
+	var errs ValidationErrors
+	validateUUID(&errs, "payoutId", i.PayoutID)
+	validateCustomerMessage(&errs, i.CustomerMessage)
+	validateTransaction(&errs, cfg, OPERATION_TYPE_PAYOUT, "recipient.accountDetails", i.Recipient.AccountDetails, i.Amount, i.Currency)
+	return errs.err()
+}
+
+// Validate checks the refund before it is sent. The refundId and depositId must be UUIDs,
+// and an amount must be in a valid format and come with a currency. The provider of the
+// refunded deposit isn't part of the request, so cfg isn't used. The returned error is a
+// ValidationErrors.
+func (i *InitiateRefundRequestBody) Validate(cfg *ActiveConfigurationResponse) error {
+	var errs ValidationErrors
+	validateUUID(&errs, "refundId", i.RefundID)
+	validateUUID(&errs, "depositId", i.DepositID)
+
+	if i.Amount != "" {
+		if _, err := ParseAmount(i.Amount); err != nil {
+			errs.add("amount", "invalid amount %q", i.Amount)
+		}
+		if i.Currency == "" {
+			errs.add("currency", "is required when amount is set")
+		}
+	}
+	return errs.err()
+}
+
+func validateUUID(errs *ValidationErrors, field, id string) {
+	if !uuidPattern.MatchString(id) {
+		errs.add(field, "must be a UUID, got %q", id)
+	}
+}
+
+func validateCustomerMessage(errs *ValidationErrors, message string) {
+	if message != "" && !customerMessagePattern.MatchString(message) {
+		errs.add("customerMessage", "must have 4 to 22 letters, digits or spaces")
+	}
+}
+
+// validateTransaction checks the account, amount and currency of a deposit or payout
+func validateTransaction(errs *ValidationErrors, cfg *ActiveConfigurationResponse, operation, accountField string, account AccountDetails, amountValue, currency string) {
+	if !phoneNumberPattern.MatchString(account.PhoneNumber) {
+		errs.add(accountField+".phoneNumber", "must only contain digits, got %q", account.PhoneNumber)
+	}
+	if account.Provider == "" {
+		errs.add(accountField+".provider", "is required")
+	}
+	if currency == "" {
+		errs.add("currency", "is required")
+	}
+
+	amount, amountErr := ParseAmount(amountValue)
+	if amountValue == "" {
+		errs.add("amount", "is required")
+	} else if amountErr != nil {
+		errs.add("amount", "invalid amount %q", amountValue)
+	}
+
+	if cfg == nil || account.Provider == "" || currency == "" {
+		return
+	}
+
+	country, provider := cfg.findProvider(account.Provider)
+	if provider == nil {
+		errs.add(accountField+".provider", "provider %s is not configured", account.Provider)
+		return
+	}
+	if country.Prefix != "" && account.PhoneNumber != "" && !strings.HasPrefix(account.PhoneNumber, country.Prefix) {
+		errs.add(accountField+".phoneNumber", "must start with the country prefix %s", country.Prefix)
+	}
+
+	operations := strings.ToLower(operation) + "s"
+	op, err := cfg.FindOperationType(account.Provider, currency, operation)
+	switch {
+	case !provider.supportsCurrency(currency):
+		errs.add("currency", "provider %s does not support %s", account.Provider, currency)
+	case err != nil:
+		errs.add(accountField+".provider", "%s is not configured for %s in %s", account.Provider, operations, currency)
+	case op.Status == OPERATION_STATUS_CLOSED:
+		errs.add(accountField+".provider", "%s is CLOSED for %s", account.Provider, operations)
+	case amountErr == nil:
+		if message := op.checkAmount(amount); message != "" {
+			errs.add("amount", "%s", message)
+		}
+	}
+}
+
+// findProvider returns a provider and its country
+func (c *ActiveConfigurationResponse) findProvider(provider string) (*CountryConfig, *ProviderConfig) {
+	for i := range c.Countries {
+		for j := range c.Countries[i].Providers {
+			if c.Countries[i].Providers[j].Provider == provider {
+				return &c.Countries[i], &c.Countries[i].Providers[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (p *ProviderConfig) supportsCurrency(currency string) bool {
+	for _, cur := range p.Currencies {
+		if cur.Currency == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// validator is a request body with a Validate method
+type validator interface {
+	Validate(cfg *ActiveConfigurationResponse) error
+}
+
+// validate runs payload.Validate when ValidateRequests is enabled. The active configuration
+// is fetched once and cached; when it can't be fetched, only the checks that don't need it run.
+func (a *Client) validate(ctx context.Context, payload validator) error {
+	if !a.validateRequests {
+		return nil
+	}
+	return payload.Validate(a.activeConfiguration(ctx))
+}
+
+// activeConfiguration returns the cached active configuration, fetching it when it is older
+// than activeConfigurationTTL. It returns nil when the configuration can't be fetched.
+func (a *Client) activeConfiguration(ctx context.Context) *ActiveConfigurationResponse {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	if a.config != nil && time.Since(a.configFetchedAt) < activeConfigurationTTL {
+		return a.config
+	}
+	if time.Since(a.configFailedAt) < activeConfigurationRetryInterval {
+		return a.config
+	}
+
+	cfg, err := a.GetActiveConfigurationContext(ctx)
+	if err != nil {
+		// Keep using a stale configuration rather than none
+		a.configFailedAt = time.Now()
+		return a.config
+	}
+	a.config = cfg
+	a.configFetchedAt = time.Now()
+	return cfg
+}
@@ -0,0 +1,194 @@
+package pawapaygo
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/salticon/pawapay-go-sdk/sfv"
+)
+
+// PublicKeyResolver looks up the public key pawaPay signed a callback with
+type PublicKeyResolver interface {
+	PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+}
+
+// PublicKeys is a fixed set of public keys by key ID
+type PublicKeys map[string]crypto.PublicKey
+
+// PublicKey returns the key with the given ID
+func (k PublicKeys) PublicKey(_ context.Context, keyID string) (crypto.PublicKey, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+	return key, nil
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS#1 (RSA) public key in PEM format
+func ParsePublicKeyPEM(publicKeyPEM []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in public key")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("failed to parse public key of type %q", block.Type)
+}
+
+// VerifySignature verifies the RFC 9421 signature of a callback sent by pawaPay. body must be
+// the raw request body as received. The signature base is rebuilt from the components listed
+// in Signature-Input, the Content-Digest header is checked against body and the signature is
+// verified with the key resolved from the keyid parameter. The returned error wraps
+// ErrInvalidSignature and describes why verification failed.
+func VerifySignature(r *http.Request, body []byte, keys PublicKeyResolver) error {
+	input, err := parseSignatureInput(r.Header.Get("Signature-Input"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	signature, err := parseSignature(r.Header.Get("Signature"), input.label)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	if input.expires != 0 && time.Now().Unix() > input.expires {
+		return fmt.Errorf("%w: signature expired at %s", ErrInvalidSignature, time.Unix(input.expires, 0).UTC().Format(time.RFC3339))
+	}
+
+	// The body is only protected when its digest is covered by the signature
+	if len(body) > 0 {
+		if !input.covers("content-digest") {
+			return fmt.Errorf("%w: content-digest is not covered by the signature", ErrInvalidSignature)
+		}
+		if err := VerifyContentDigest(r.Header.Get("Content-Digest"), body); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+	}
+
+	if input.keyID == "" {
+		return fmt.Errorf("%w: missing keyid parameter", ErrInvalidSignature)
+	}
+	key, err := keys.PublicKey(r.Context(), input.keyID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	alg := input.alg
+	if alg == "" {
+		if alg, err = signatureAlgorithm(key); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+	}
+
+	signatureBase, _, err := buildSignatureBase(r, nil, input.list)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	if err := verifyMessage(alg, key, []byte(signatureBase), signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
+
+// signatureInput holds one parsed member of a Signature-Input header
+type signatureInput struct {
+	label   string
+	list    sfv.InnerList // Covered components and signature parameters
+	alg     string
+	created int64
+	expires int64
+	keyID   string
+}
+
+func (s *signatureInput) covers(name string) bool {
+	for _, item := range s.list.Items {
+		if item.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSignatureInput parses a Signature-Input header. The pawaPay signature (sig-pp) is
+// used when present, otherwise the first signature in the header.
+func parseSignatureInput(header string) (*signatureInput, error) {
+	if header == "" {
+		return nil, fmt.Errorf("missing Signature-Input header")
+	}
+
+	dict, err := sfv.ParseDictionary(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Signature-Input header: %v", err)
+	}
+	if len(dict) == 0 {
+		return nil, fmt.Errorf("empty Signature-Input header")
+	}
+
+	member := dict[0]
+	if m, ok := dict.Get(signatureLabel); ok {
+		member = sfv.DictMember{Key: signatureLabel, Value: m}
+	}
+
+	list, ok := member.Value.(sfv.InnerList)
+	if !ok {
+		return nil, fmt.Errorf("signature %s is not an inner list", member.Key)
+	}
+
+	input := &signatureInput{label: member.Key, list: list}
+	for _, param := range list.Params {
+		var ok bool
+		switch param.Key {
+		case "alg":
+			input.alg, ok = param.Value.(string)
+		case "keyid":
+			input.keyID, ok = param.Value.(string)
+		case "created":
+			input.created, ok = param.Value.(int64)
+		case "expires":
+			input.expires, ok = param.Value.(int64)
+		default:
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid %s parameter %v", param.Key, param.Value)
+		}
+	}
+
+	return input, nil
+}
+
+// parseSignature returns the signature with the given label from a Signature header
+func parseSignature(header, label string) ([]byte, error) {
+	if header == "" {
+		return nil, fmt.Errorf("missing Signature header")
+	}
+
+	dict, err := sfv.ParseDictionary(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Signature header: %v", err)
+	}
+
+	member, ok := dict.Get(label)
+	if !ok {
+		return nil, fmt.Errorf("no signature labelled %q", label)
+	}
+	item, ok := member.(sfv.Item)
+	signature, isBytes := item.Value.([]byte)
+	if !ok || !isBytes {
+		return nil, fmt.Errorf("signature %s is not a byte sequence", label)
+	}
+	return signature, nil
+}
@@ -0,0 +1,238 @@
+package pawapaygo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookHandler is an http.Handler for deposit, payout and refund callbacks. It reads the
+// raw body, verifies the signature when Keys is set, decodes the callback and calls the
+// function registered for its type and status.
+//
+// pawaPay redelivers callbacks that aren't answered with 2xx, so the handler answers with:
+//   - 200 OK when the callback was handled, or no function is registered for it, or it was
+//     already processed according to Idempotency, or its signature is older than MaxSignatureAge
+//   - 400 Bad Request when the body isn't a valid callback
+//   - 401 Unauthorized when the signature is missing or invalid
+//   - 500 Internal Server Error when the registered function returns an error, or
+//     MaxSignatureAge is set without Keys
+//
+// Use gin.WrapH(handler) to mount it on a gin router.
+type WebhookHandler struct {
+	// Keys verifies callback signatures when set, e.g. NewKeyStore(client)
+	Keys PublicKeyResolver
+
+	// Deposit callbacks of the v1 API are converted to the v2 model, see ParseDepositCallback
+	OnDepositCompleted func(ctx context.Context, callback *DepositCallback) error
+	OnDepositFailed    func(ctx context.Context, callback *DepositCallback) error
+	OnPayoutCompleted  func(ctx context.Context, callback *PayoutCallback) error
+	OnPayoutFailed     func(ctx context.Context, callback *PayoutCallback) error
+	OnRefundCompleted  func(ctx context.Context, callback *RefundCallback) error
+	OnRefundFailed     func(ctx context.Context, callback *RefundCallback) error
+
+	// Idempotency records processed callbacks when set, so callbacks delivered more than once
+	// are acknowledged without calling the registered function again
+	Idempotency IdempotencyStore
+	// MaxSignatureAge acknowledges callbacks without processing them when the created
+	// parameter of their signature is older, to prevent replays. Requires Keys; without them
+	// every callback is answered with 500 Internal Server Error.
+	MaxSignatureAge time.Duration
+
+	// OnError is called with every error that makes the handler reject a callback, and with
+	// ErrStaleCallback for callbacks skipped because of MaxSignatureAge
+	OnError func(r *http.Request, err error)
+}
+
+var _ http.Handler = (*WebhookHandler)(nil)
+
+// ServeHTTP handles a single callback
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, err := h.handle(w, r)
+	if err != nil && h.OnError != nil {
+		h.OnError(r, err)
+	}
+
+	w.WriteHeader(status)
+}
+
+func (h *WebhookHandler) handle(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("unexpected callback method %s", r.Method)
+	}
+	// Without verified signatures the created parameter can't be trusted, so replay
+	// protection would silently be off
+	if h.MaxSignatureAge > 0 && h.Keys == nil {
+		return http.StatusInternalServerError, errors.New("WebhookHandler.MaxSignatureAge requires Keys")
+	}
+
+	body, ok := RawBody(r)
+	if !ok {
+		var err error
+		body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, MaxCallbackBodySize))
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("failed to read callback body: %w", err)
+		}
+	}
+
+	if h.Keys != nil {
+		if err := VerifySignature(r, body, h.Keys); err != nil {
+			return http.StatusUnauthorized, err
+		}
+
+		if h.MaxSignatureAge > 0 {
+			// The signature was verified, so its Signature-Input is valid
+			input, _ := parseSignatureInput(r.Header.Get("Signature-Input"))
+			if input.created == 0 {
+				return http.StatusOK, fmt.Errorf("%w: signature has no created parameter", ErrStaleCallback)
+			}
+			if created := time.Unix(input.created, 0); time.Since(created) > h.MaxSignatureAge {
+				return http.StatusOK, fmt.Errorf("%w: signed at %s", ErrStaleCallback, created.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+
+	if err := h.dispatch(r.Context(), body); err != nil {
+		var decodeErr *callbackDecodeError
+		if errors.As(err, &decodeErr) {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// callbackDecodeError reports a callback body that can't be decoded
+type callbackDecodeError struct {
+	err error
+}
+
+func (e *callbackDecodeError) Error() string {
+	return "invalid callback body: " + e.err.Error()
+}
+
+func (e *callbackDecodeError) Unwrap() error {
+	return e.err
+}
+
+// dispatch decodes the callback and calls the function registered for its type and status
+func (h *WebhookHandler) dispatch(ctx context.Context, body []byte) error {
+	// Refund callbacks also carry the depositId of the refunded deposit
+	ids := struct {
+		DepositID string `json:"depositId"`
+		PayoutID  string `json:"payoutId"`
+		RefundID  string `json:"refundId"`
+		Status    string `json:"status"`
+	}{}
+	if err := json.Unmarshal(body, &ids); err != nil {
+		return &callbackDecodeError{err}
+	}
+
+	switch {
+	case ids.RefundID != "":
+		key := "refund:" + ids.RefundID + ":" + ids.Status
+		return dispatchCallback(ctx, h.Idempotency, key, body, ids.Status, decodeJSON[RefundCallback], h.OnRefundCompleted, h.OnRefundFailed)
+	case ids.PayoutID != "":
+		key := "payout:" + ids.PayoutID + ":" + ids.Status
+		return dispatchCallback(ctx, h.Idempotency, key, body, ids.Status, decodeJSON[PayoutCallback], h.OnPayoutCompleted, h.OnPayoutFailed)
+	case ids.DepositID != "":
+		key := "deposit:" + ids.DepositID + ":" + ids.Status
+		return dispatchCallback(ctx, h.Idempotency, key, body, ids.Status, ParseDepositCallback, h.OnDepositCompleted, h.OnDepositFailed)
+	}
+	return &callbackDecodeError{errors.New("no depositId, payoutId or refundId")}
+}
+
+// dispatchCallback decodes the callback and calls the function registered for its status.
+// When store is set, the function is called until a delivery of key was processed successfully;
+// concurrent deliveries are acknowledged while one of them is in progress.
+func dispatchCallback[T any](ctx context.Context, store IdempotencyStore, key string, body []byte, status string, decode func([]byte) (*T, error), onCompleted, onFailed func(context.Context, *T) error) error {
+	var fn func(context.Context, *T) error
+	switch status {
+	case "COMPLETED":
+		fn = onCompleted
+	case "FAILED":
+		fn = onFailed
+	}
+	if fn == nil {
+		return nil
+	}
+
+	callback, err := decode(body)
+	if err != nil {
+		return &callbackDecodeError{err}
+	}
+
+	if store == nil {
+		return fn(ctx, callback)
+	}
+
+	claimed, err := store.Claim(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to claim callback %s: %w", key, err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	// Release the key when fn panics, so pawaPay's redelivery is processed again. The panic
+	// isn't recovered and keeps its stack trace.
+	returned := false
+	defer func() {
+		if !returned {
+			store.Release(ctx, key)
+		}
+	}()
+
+	err = fn(ctx, callback)
+	returned = true
+
+	// Release the key so pawaPay's redelivery is processed again
+	if err != nil {
+		if releaseErr := store.Release(ctx, key); releaseErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to release callback %s: %w", key, releaseErr))
+		}
+		return err
+	}
+
+	// The callback was processed, so a failure here only risks processing a redelivery again
+	if err := store.Complete(ctx, key); err != nil {
+		return fmt.Errorf("failed to complete callback %s: %w", key, err)
+	}
+	return nil
+}
+
+func decodeJSON[T any](body []byte) (*T, error) {
+	v := new(T)
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ParseDepositCallback decodes the body of a deposit callback. Callbacks of the v1 API, which
+// carry correspondent and requestedAmount instead of payer.accountDetails and amount, are
+// converted to the v2 model.
+func ParseDepositCallback(body []byte) (*DepositCallback, error) {
+	version := struct {
+		Correspondent   *string `json:"correspondent"`
+		RequestedAmount *string `json:"requestedAmount"`
+	}{}
+	if err := json.Unmarshal(body, &version); err != nil {
+		return nil, err
+	}
+
+	if version.Correspondent != nil || version.RequestedAmount != nil {
+		legacy, err := decodeJSON[DepositCallbackRequestBody](body)
+		if err != nil {
+			return nil, err
+		}
+		return legacy.ToV2(), nil
+	}
+
+	return decodeJSON[DepositCallback](body)
+}